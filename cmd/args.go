@@ -17,7 +17,9 @@ const (
 type App struct {
 	Merge *MergeCmd `arg:"subcommand:merge" help:"combines a template with one or more env files"`
 	Marks *MarksCmd `arg:"subcommand:marks" help:"shows all placeholders defined in the specified template"`
-	Vars  *VarsCmd  `arg:"subcommand:vars" help:"shows all built-in (and eventually user defined) variables"`
+	Vars  *VarsCmd  `arg:"subcommand:vars" help:"shows all built-in (and eventually user defined) variables; 'vars set' and 'vars unset' edit env files"`
+	Cache *CacheCmd `arg:"subcommand:cache" help:"manages the cache of remote templates and env files"`
+	Sign  *SignCmd  `arg:"subcommand:sign" help:"signs templates (or generates a signing key pair)"`
 }
 
 func (App) Description() string {
@@ -36,6 +38,10 @@ func Run() error {
 		return app.Marks.Run()
 	case app.Merge != nil:
 		return app.Merge.Run()
+	case app.Cache != nil:
+		return app.Cache.Run()
+	case app.Sign != nil:
+		return app.Sign.Run()
 	default:
 		p.WriteHelp(os.Stdout)
 	}
@@ -2,11 +2,11 @@ package cmd
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"runtime"
 	"time"
 
-	"github.com/lucasepe/tbd/pkg/data"
 	"github.com/lucasepe/tbd/pkg/dotenv"
 	"github.com/lucasepe/tbd/pkg/vcs"
 )
@@ -17,32 +17,48 @@ const (
 	ARCH      = "ARCH"
 )
 
-func builtinVars() (map[string]string, error) {
+// builtinVars returns the built-in variables, including the ones computed
+// by the first provider handling repo or, if empty, the working directory
+// (where a missing repository is just a warning), and the warnings
+// about the repository variables which cannot be computed.
+func builtinVars(prov dotenv.Provenance, opts vcs.Options, providers []vcs.Provider, repo string) (map[string]string, []vcs.Warning, error) {
 	meta := map[string]string{}
 	meta[TimeStamp] = time.Now().Local().UTC().Format(time.RFC3339)
 	meta[OS] = runtime.GOOS
 	meta[ARCH] = runtime.GOARCH
 
-	if cwd, err := os.Getwd(); err == nil {
-		vcs.GitRepoMetadata(cwd, meta)
+	var warnings []vcs.Warning
+	if repo != "" {
+		var err error
+		if warnings, err = vcs.CollectMetadata(repo, opts, meta, providers...); err != nil {
+			return nil, nil, fmt.Errorf("cannot read the repository at '%s': %w", repo, err)
+		}
+	} else if cwd, err := os.Getwd(); err == nil {
+		if warnings, err = vcs.CollectMetadata(cwd, opts, meta, providers...); err != nil {
+			warnings = []vcs.Warning{{Topic: "repository", Err: err}}
+		}
+	}
+
+	for k := range meta {
+		prov.Add(k, dotenv.Origin{Source: dotenv.Builtin})
 	}
 
-	return meta, nil
+	return meta, warnings, nil
 }
 
-func userVars(vars map[string]string, envfile ...string) error {
+func userVars(vars map[string]string, fetch fetchFunc, p dotenv.Parser, envfile ...string) error {
 	if len(envfile) <= 0 {
 		return nil
 	}
 
 	for _, el := range envfile {
-		const maxFileSize int64 = 512 * 1000
-		buf, err := data.Fetch(el, maxFileSize)
+		buf, err := fetch(el)
 		if err != nil {
 			return err
 		}
 
-		if err := dotenv.ParseInto(bytes.NewBuffer(buf), vars); err != nil {
+		p.Source = el
+		if err := p.ParseInto(bytes.NewBuffer(buf), vars); err != nil {
 			return err
 		}
 	}
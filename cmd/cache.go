@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lucasepe/tbd/pkg/data"
+	"github.com/lucasepe/tbd/pkg/table"
+)
+
+type CacheCmd struct {
+	List  *CacheListCmd  `arg:"subcommand:list" help:"shows all cached remote templates and env files"`
+	Clear *CacheClearCmd `arg:"subcommand:clear" help:"removes all cached remote templates and env files"`
+}
+
+func (c *CacheCmd) Run() error {
+	cache, err := defaultCache()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.List != nil:
+		return c.List.Run(cache)
+	case c.Clear != nil:
+		return c.Clear.Run(cache)
+	}
+
+	return fmt.Errorf("missing cache subcommand (list or clear)")
+}
+
+type CacheListCmd struct{}
+
+func (c *CacheListCmd) Run(cache *data.Cache) error {
+	list, err := cache.List()
+	if err != nil {
+		return err
+	}
+
+	tbl := &table.TextTable{}
+	tbl.SetHeader("URL", "Size", "Fetched", "ETag")
+
+	for _, el := range list {
+		tbl.AddRow(el.URL, fmt.Sprintf("%d", el.Size), el.Fetched.Local().Format(time.RFC3339), el.ETag)
+	}
+
+	fmt.Println(tbl.Draw())
+
+	return nil
+}
+
+type CacheClearCmd struct{}
+
+func (c *CacheClearCmd) Run(cache *data.Cache) error {
+	n, err := cache.Clear()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d cached entries removed from %s\n", n, cache.Dir)
+	return nil
+}
+
+func defaultCache() (*data.Cache, error) {
+	dir, err := data.DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &data.Cache{Dir: dir}, nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/lucasepe/tbd/pkg/data"
+)
+
+// Exit codes returned by the tool.
+const (
+	ExitFailure     = -1
+	ExitNotFound    = 3
+	ExitServerError = 4
+)
+
+// ExitCode returns the process exit code for the specified error.
+func ExitCode(err error) int {
+	var herr *data.HTTPError
+	if errors.As(err, &herr) {
+		switch {
+		case herr.NotFound():
+			return ExitNotFound
+		case herr.ServerError():
+			return ExitServerError
+		}
+		return ExitFailure
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		return ExitNotFound
+	}
+
+	return ExitFailure
+}
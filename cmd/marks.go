@@ -3,17 +3,17 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/lucasepe/tbd/pkg/data"
 	"github.com/lucasepe/tbd/pkg/template"
 )
 
 type MarksCmd struct {
+	fetchOptions
+	templateOptions
 	Template string `arg:"positional,required" placeholder:"TEMPLATE"`
 }
 
 func (c *MarksCmd) Run() error {
-	const maxFileSize int64 = 512 * 1000
-	tpl, err := data.Fetch(c.Template, maxFileSize)
+	tpl, err := c.fetchTemplate(&c.fetchOptions, c.Template)
 	if err != nil {
 		return err
 	}
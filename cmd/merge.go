@@ -1,33 +1,64 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
-	"github.com/lucasepe/tbd/pkg/data"
 	"github.com/lucasepe/tbd/pkg/template"
 )
 
 type MergeCmd struct {
-	Template string   `arg:"positional,required" placeholder:"TEMPLATE"`
-	EnvFiles []string `arg:"positional" placeholder:"ENV_FILE"`
+	envOptions
+	fetchOptions
+	repoOptions
+	templateOptions
+	RepoFromTemplate bool     `arg:"--repo-from-template" help:"computes the REPO_* variables from the repository containing the (local) template"`
+	Template         string   `arg:"positional,required" placeholder:"TEMPLATE"`
+	EnvFiles         []string `arg:"positional" placeholder:"ENV_FILE"`
 }
 
 func (c *MergeCmd) Run() error {
-	meta, err := builtinVars()
+	fetch, err := c.fetch()
 	if err != nil {
 		return err
 	}
 
-	if err := userVars(meta, c.EnvFiles...); err != nil {
+	tpl, err := c.fetchTemplate(&c.fetchOptions, c.Template)
+	if err != nil {
+		return err
+	}
+
+	repo := c.Repo
+	if c.RepoFromTemplate {
+		if repo != "" {
+			return fmt.Errorf("--repo and --repo-from-template are mutually exclusive")
+		}
+		if repo, err = repoFromTemplate(c.Template); err != nil {
+			return err
+		}
+	}
+
+	opts, err := c.vcs()
+	if err != nil {
 		return err
 	}
 
-	const maxFileSize int64 = 512 * 1000
-	tpl, err := data.Fetch(c.Template, maxFileSize)
+	providers, err := c.providers(repo)
 	if err != nil {
 		return err
 	}
 
+	meta, warnings, err := builtinVars(nil, opts, providers, repo)
+	if err != nil {
+		return err
+	}
+	c.report(warnings)
+
+	p := c.parser(nil, os.Stderr)
+	if err := userVars(meta, fetch, p, c.EnvFiles...); err != nil {
+		return err
+	}
+
 	env := make(map[string]interface{})
 	for k, v := range meta {
 		env[k] = v
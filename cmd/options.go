@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lucasepe/tbd/pkg/data"
+	"github.com/lucasepe/tbd/pkg/dotenv"
+	"github.com/lucasepe/tbd/pkg/vcs"
+)
+
+// fetchOptions are the flags controlling how templates
+// and env files are fetched from HTTP URLs.
+type fetchOptions struct {
+	Headers        []string      `arg:"--header,separate" placeholder:"NAME:VALUE" help:"adds an header to HTTP requests"`
+	BearerTokenEnv string        `arg:"--bearer-token-env" placeholder:"VAR" help:"sends the bearer token stored in the specified environment variable"`
+	User           string        `arg:"--user" placeholder:"USER[:PASSWORD]" help:"HTTP basic authentication credentials"`
+	Netrc          bool          `arg:"--netrc" help:"looks up HTTP credentials in the .netrc file"`
+	Timeout        time.Duration `arg:"--timeout" default:"60s" help:"maximum duration of each HTTP request"`
+	Retries        int           `arg:"--retries" default:"2" help:"number of retries on network errors and temporary HTTP failures"`
+	RetryBackoff   time.Duration `arg:"--retry-backoff" default:"500ms" help:"wait before the first retry, doubled at each attempt"`
+	MaxSize        byteSize      `arg:"--max-size" default:"512KB" help:"maximum size of templates and env files (0 means no limit)"`
+	NoCache        bool          `arg:"--no-cache" help:"does not cache remote templates and env files"`
+	Offline        bool          `arg:"--offline" help:"uses only cached remote templates and env files"`
+	NoRemote       bool          `arg:"--no-remote,env:TBD_NO_REMOTE" help:"forbids fetching remote templates and env files"`
+	AllowedHosts   []string      `arg:"--allow-host,separate,env:TBD_ALLOW_HOSTS" placeholder:"HOST" help:"allows fetching only from the specified hosts (i.e. *.example.com)"`
+	FSRoot         string        `arg:"--fs-root,env:TBD_FS_ROOT" placeholder:"DIR" help:"forbids reading local files outside of the specified directory"`
+	AllowedEnv     []string      `arg:"--allow-env,separate,env:TBD_ALLOW_ENV" placeholder:"VAR" help:"allows env:// URIs to read only the specified variables (i.e. TBD_*)"`
+}
+
+// fetchFunc gets the content of a template or an env file.
+type fetchFunc func(uri string) ([]byte, error)
+
+func (o *fetchOptions) fetch() (fetchFunc, error) {
+	f, err := o.fetcher()
+	if err != nil {
+		return nil, err
+	}
+
+	return o.fetchWith(f), nil
+}
+
+func (o *fetchOptions) fetchWith(f *data.Fetcher) fetchFunc {
+	limit := int64(o.MaxSize)
+	return func(uri string) ([]byte, error) {
+		buf, err := f.Fetch(uri, limit)
+		if errors.Is(err, data.ErrLimitExceeded) {
+			return nil, fmt.Errorf("%w (use --max-size to raise the limit)", err)
+		}
+		return buf, err
+	}
+}
+
+// templateOptions are the flags protecting the template
+// from unexpected or unauthorized changes.
+type templateOptions struct {
+	Checksum        string   `arg:"--checksum" placeholder:"ALGO:HEX" help:"expected digest of the template (i.e. sha256:ab12...)"`
+	VerifySignature bool     `arg:"--verify-signature" help:"requires a valid signature (TEMPLATE.sig) made with a trusted key"`
+	TrustedKeys     []string `arg:"--trusted-key,separate" placeholder:"FILE" help:"trusts the specified public key (in addition to the ones in the tbd config dir)"`
+}
+
+// fetchTemplate gets the template verifying its checksum and signature.
+func (o *templateOptions) fetchTemplate(fo *fetchOptions, uri string) ([]byte, error) {
+	f, err := fo.fetcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Checksum != "" {
+		if uri, err = data.WithChecksum(uri, o.Checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.VerifySignature {
+		if f.Verifier, err = o.verifier(); err != nil {
+			return nil, err
+		}
+	}
+
+	return fo.fetchWith(f)(uri)
+}
+
+func (o *templateOptions) verifier() (*data.Verifier, error) {
+	v := &data.Verifier{}
+
+	if dir, err := trustedKeysDir(); err == nil {
+		keys, err := data.LoadPublicKeys(dir)
+		if err != nil {
+			return nil, err
+		}
+		v.Keys = append(v.Keys, keys...)
+	}
+
+	for _, el := range o.TrustedKeys {
+		pk, err := data.LoadPublicKey(el)
+		if err != nil {
+			return nil, err
+		}
+		v.Keys = append(v.Keys, pk)
+	}
+
+	if len(v.Keys) == 0 {
+		return nil, fmt.Errorf("no trusted public keys, use --trusted-key or add them to the tbd config dir")
+	}
+
+	return v, nil
+}
+
+// trustedKeysDir returns the directory holding the trusted public keys.
+func trustedKeysDir() (string, error) {
+	dir, err := data.DefaultConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trusted"), nil
+}
+
+func (o *fetchOptions) fetcher() (*data.Fetcher, error) {
+	f := &data.Fetcher{
+		Header:  http.Header{},
+		Timeout: o.Timeout,
+		Retries: o.Retries,
+		Backoff: o.RetryBackoff,
+	}
+
+	for _, el := range o.Headers {
+		idx := strings.Index(el, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid header '%s', expected NAME:VALUE", el)
+		}
+		f.Header.Add(strings.TrimSpace(el[:idx]), strings.TrimSpace(el[idx+1:]))
+	}
+
+	if o.BearerTokenEnv != "" {
+		token := os.Getenv(o.BearerTokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("environment variable '%s' is not set", o.BearerTokenEnv)
+		}
+		f.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if o.User != "" {
+		f.Username = o.User
+		if idx := strings.Index(o.User, ":"); idx != -1 {
+			f.Username, f.Password = o.User[:idx], o.User[idx+1:]
+		}
+	}
+
+	if o.Netrc {
+		f.Netrc = data.NetrcPath()
+	}
+
+	if o.NoRemote || len(o.AllowedHosts) > 0 || o.FSRoot != "" || len(o.AllowedEnv) > 0 {
+		f.Policy = &data.Policy{
+			NoRemote:     o.NoRemote,
+			AllowedHosts: o.AllowedHosts,
+			Root:         o.FSRoot,
+			AllowedEnv:   o.AllowedEnv,
+		}
+	}
+
+	if o.NoCache && o.Offline {
+		return nil, fmt.Errorf("--offline requires the cache, remove --no-cache")
+	}
+
+	if !o.NoCache {
+		cache, err := defaultCache()
+		if err != nil && o.Offline {
+			return nil, err
+		}
+		if err == nil {
+			f.Cache = cache
+			f.Offline = o.Offline
+		}
+	}
+
+	return f, nil
+}
+
+// envOptions are the flags controlling how env files are evaluated.
+type envOptions struct {
+	AllowExec   bool          `arg:"--allow-exec" help:"evaluates $(...) command substitutions in env files"`
+	ExecTimeout time.Duration `arg:"--exec-timeout" default:"10s" help:"maximum running time of each command substitution"`
+}
+
+// parser returns the env files parser: command substitutions are
+// evaluated only if allowed, otherwise they are left as is and
+// reported (along with the key and the line) on w.
+func (o *envOptions) parser(prov dotenv.Provenance, w io.Writer) dotenv.Parser {
+	return dotenv.Parser{
+		Provenance: prov,
+		Exec:       o.exec(),
+		Warn: func(err error) {
+			fmt.Fprintf(w, "warning: %v\n", err)
+		},
+	}
+}
+
+func (o *envOptions) exec() dotenv.ExecFunc {
+	if o.AllowExec {
+		return dotenv.ShellExec(o.ExecTimeout)
+	}
+
+	return func(command string) (string, error) {
+		return "", fmt.Errorf("$(%s) left as is: %w (use --allow-exec to evaluate it)", command, dotenv.ErrExecRefused)
+	}
+}
+
+// repoOptions are the flags controlling the variables
+// computed from the Git repository.
+type repoOptions struct {
+	IgnoreUntracked bool     `arg:"--ignore-untracked" help:"does not count untracked files as uncommitted changes"`
+	TagPattern      string   `arg:"--tag-pattern" placeholder:"GLOB" help:"considers only the tags matching the pattern (i.e. api/v*)"`
+	Conventional    bool     `arg:"--conventional-commits" help:"infers REPO_NEXT_VERSION from the Conventional Commit messages since the latest tag"`
+	Remote          string   `arg:"--remote" placeholder:"NAME" help:"remote used for the repository URL variables (default: origin, upstream or the first one)"`
+	Repo            string   `arg:"--repo" placeholder:"PATH" help:"repository the REPO_* variables are computed from (default: the working directory)"`
+	Base            string   `arg:"--base" placeholder:"REF" help:"revision REPO_CHANGED_PATHS is computed from (default: the latest tag)"`
+	Components      []string `arg:"--component,separate" placeholder:"NAME=PATH" help:"flags the component with REPO_CHANGED_<NAME> if files under PATH (or matching the glob) changed"`
+	Provider        string   `arg:"--provider" placeholder:"NAME" help:"source of the REPO_* variables: git, snapshot or ci (default: the first detected)"`
+	Verbose         bool     `arg:"--verbose" help:"reports the REPO_* variables which cannot be computed"`
+}
+
+func (o *repoOptions) vcs() (vcs.Options, error) {
+	components, err := vcs.ParseComponents(o.Components)
+	if err != nil {
+		return vcs.Options{}, err
+	}
+
+	return vcs.Options{
+		IgnoreUntracked:     o.IgnoreUntracked,
+		TagPattern:          o.TagPattern,
+		ConventionalCommits: o.Conventional,
+		Remote:              o.Remote,
+		Base:                o.Base,
+		Components:          components,
+	}, nil
+}
+
+// providers returns the providers of the REPO_* variables.
+// providers returns the providers of the REPO_* variables: the CI environment
+// describes the working directory, so it is used for an explicit repo only
+// when requested with --provider.
+func (o *repoOptions) providers(repo string) ([]vcs.Provider, error) {
+	if o.Provider == "" && repo == "" {
+		return vcs.DefaultProviders(), nil
+	}
+
+	if o.Provider == "" {
+		res := []vcs.Provider{}
+		for _, el := range vcs.DefaultProviders() {
+			if _, ok := el.(*vcs.CIProvider); !ok {
+				res = append(res, el)
+			}
+		}
+		return res, nil
+	}
+
+	p, err := vcs.ProviderByName(o.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return []vcs.Provider{p}, nil
+}
+
+// report prints the warnings on stderr (only if verbose).
+func (o *repoOptions) report(warnings []vcs.Warning) {
+	if !o.Verbose {
+		return
+	}
+
+	for _, el := range warnings {
+		if len(el.Vars) > 0 {
+			fmt.Fprintf(os.Stderr, "warning: %v (affects %s)\n", el, strings.Join(el.Vars, ", "))
+		} else {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", el)
+		}
+	}
+}
+
+// repoFromTemplate returns the directory of a local template (a path
+// or a file URI, see data.LocalPath), so that its repository can be used.
+func repoFromTemplate(uri string) (string, error) {
+	if uri == "-" {
+		return "", fmt.Errorf("cannot find the repository of the standard input: --repo-from-template requires a local template")
+	}
+
+	filename, err := data.LocalPath(uri)
+	if err != nil {
+		return "", fmt.Errorf("cannot find the repository of '%s': --repo-from-template requires a local template (%v)", uri, err)
+	}
+
+	return filepath.Dir(filename), nil
+}
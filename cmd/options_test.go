@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucasepe/tbd/pkg/vcs"
+)
+
+func TestRepoFromTemplate(t *testing.T) {
+	digest := "a2b5bd5d5f0eb4d0b7fd90d1b7c6a4bd4a5d4f1b2bba01eb0a4fe50e44a24bd4"
+
+	tests := []struct {
+		uri  string
+		want string
+		err  bool
+	}{
+		{"tpl/pod.tbd", "tpl", false},
+		{"pod.tbd", ".", false},
+		{"tpl/#shared/pod.tbd", "tpl/#shared", false},
+		{"file:///srv/tpl/pod.tbd", "/srv/tpl", false},
+		{"file://localhost/srv/tpl/pod.tbd", "/srv/tpl", false},
+		{"tpl/pod.tbd#sha256=" + digest, "tpl", false},
+		{"file:///srv/tpl/pod.tbd#sha256=" + digest, "/srv/tpl", false},
+		{"file://h/srv/tpl/pod.tbd", "", true},
+		{"https://example.com/tpl/pod.tbd", "", true},
+		{"-", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := repoFromTemplate(tc.uri)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.uri, err)
+			continue
+		}
+		if got != filepath.FromSlash(tc.want) {
+			t.Errorf("%s: got [%v] want [%v]", tc.uri, got, tc.want)
+		}
+	}
+}
+
+func TestEnvOptionsRefuseExec(t *testing.T) {
+	var warnings bytes.Buffer
+	o := &envOptions{}
+	p := o.parser(nil, &warnings)
+	p.Source = "prod.vars"
+
+	env := map[string]string{}
+	if err := p.ParseInto(strings.NewReader("A=1\nHOST=$(hostname)\n"), env); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := env["HOST"]; got != "$(hostname)" {
+		t.Errorf("got [%v] want [$(hostname)]", got)
+	}
+
+	want := "warning: prod.vars:2: HOST: $(hostname) left as is: command substitution not allowed (use --allow-exec to evaluate it)\n"
+	if got := warnings.String(); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestRepoOptionsProviders(t *testing.T) {
+	os.Setenv("GITHUB_SHA", "0123456789abcdef0123456789abcdef01234567")
+	defer os.Unsetenv("GITHUB_SHA")
+
+	dir := t.TempDir()
+
+	o := &repoOptions{}
+	providers, err := o.providers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = builtinVars(nil, vcs.Options{}, providers, dir)
+	if err == nil || !strings.Contains(err.Error(), "no version control metadata found") {
+		t.Errorf("expected no metadata error, got %v", err)
+	}
+
+	o.Provider = "ci"
+	if providers, err = o.providers(dir); err != nil {
+		t.Fatal(err)
+	}
+	meta, _, err := builtinVars(nil, vcs.Options{}, providers, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := meta[vcs.RepoProvider]; got != "ci" {
+		t.Errorf("got [%v] want [ci]", got)
+	}
+}
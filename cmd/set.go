@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+	"github.com/lucasepe/tbd/pkg/dotenv"
+)
+
+// varsEditApp are the 'vars set' and 'vars unset' commands, parsed from
+// the VarsCmd positionals since go-arg doesn't allow subcommands along
+// with them.
+type varsEditApp struct {
+	Set   *SetCmd   `arg:"subcommand:set" help:"sets one or more variables in an env file, preserving its layout"`
+	Unset *UnsetCmd `arg:"subcommand:unset" help:"removes one or more variables from an env file, preserving its layout"`
+}
+
+// isVarsEdit reports whether the VarsCmd positionals are a 'vars set'
+// or 'vars unset' command.
+func isVarsEdit(args []string) bool {
+	return len(args) > 0 && (args[0] == "set" || args[0] == "unset")
+}
+
+func runVarsEdit(args []string) error {
+	var app varsEditApp
+
+	p, err := arg.NewParser(arg.Config{Program: "tbd vars"}, &app)
+	if err != nil {
+		return err
+	}
+
+	err = p.Parse(args)
+	switch {
+	case err == arg.ErrHelp:
+		p.WriteHelp(os.Stdout)
+		return nil
+	case err != nil:
+		p.WriteUsage(os.Stderr)
+		return fmt.Errorf("vars %s: %w", args[0], err)
+	}
+
+	if app.Set != nil {
+		return app.Set.Run()
+	}
+	return app.Unset.Run()
+}
+
+type SetCmd struct {
+	EnvFile string   `arg:"positional,required" placeholder:"ENV_FILE"`
+	Pairs   []string `arg:"positional,required" placeholder:"KEY=VALUE"`
+}
+
+func (c *SetCmd) Run() error {
+	return editEnvFile(c.EnvFile, func(doc *dotenv.Document) error {
+		for _, el := range c.Pairs {
+			idx := strings.Index(el, "=")
+			if idx <= 0 {
+				return fmt.Errorf("invalid assignment '%s', expected KEY=VALUE", el)
+			}
+			doc.Set(el[:idx], el[idx+1:])
+		}
+		return nil
+	})
+}
+
+type UnsetCmd struct {
+	EnvFile string   `arg:"positional,required" placeholder:"ENV_FILE"`
+	Keys    []string `arg:"positional,required" placeholder:"KEY"`
+}
+
+func (c *UnsetCmd) Run() error {
+	return editEnvFile(c.EnvFile, func(doc *dotenv.Document) error {
+		for _, k := range c.Keys {
+			doc.Delete(k)
+		}
+		return nil
+	})
+}
+
+// editEnvFile applies fn to the specified local env file (created
+// if missing) and writes it back preserving its layout.
+func editEnvFile(filename string, fn func(doc *dotenv.Document) error) error {
+	mode := os.FileMode(0644)
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode()
+	}
+
+	doc, err := dotenv.ParseDocument(bytes.NewReader(buf))
+	if err != nil {
+		var perr *dotenv.ParseError
+		if errors.As(err, &perr) {
+			perr.Source = filename
+		}
+		return err
+	}
+
+	if err := fn(doc); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, doc.Bytes(), mode)
+}
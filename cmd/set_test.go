@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexflint/go-arg"
+)
+
+func TestVarsEdit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prod.vars")
+
+	run := func(args ...string) error {
+		var app App
+		p, err := arg.NewParser(arg.Config{Program: "tbd"}, &app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return app.Vars.Run()
+	}
+
+	if err := run("vars", "set", filename, "A=1", "B=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("vars", "unset", filename, "A"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), "B=2\n"; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"vars", "set"},
+		{"vars", "set", filename},
+		{"vars", "set", filename, "A"},
+		{"vars", "unset", filename},
+	} {
+		if err := run(args...); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
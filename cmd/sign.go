@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasepe/tbd/pkg/data"
+)
+
+type SignCmd struct {
+	Generate  bool     `arg:"--generate,-G" help:"generates a new key pair"`
+	SecretKey string   `arg:"--secret-key,-s" placeholder:"FILE" help:"secret key file [default: tbd.key in the tbd config dir]"`
+	PublicKey string   `arg:"--public-key,-p" placeholder:"FILE" help:"public key file written by --generate [default: tbd.pub in the tbd config dir]"`
+	Comment   string   `arg:"--comment,-t" help:"trusted comment added to the signatures [default: timestamp and file name]"`
+	Files     []string `arg:"positional" placeholder:"FILE"`
+}
+
+func (c *SignCmd) Run() error {
+	if err := c.defaults(); err != nil {
+		return err
+	}
+
+	if c.Generate {
+		if err := c.generate(); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Files) == 0 {
+		if !c.Generate {
+			return fmt.Errorf("nothing to sign")
+		}
+		return nil
+	}
+
+	buf, err := ioutil.ReadFile(c.SecretKey)
+	if err != nil {
+		return err
+	}
+
+	sk, err := data.ParseSecretKey(buf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.SecretKey, err)
+	}
+
+	for _, el := range c.Files {
+		content, err := ioutil.ReadFile(el)
+		if err != nil {
+			return err
+		}
+
+		comment := c.Comment
+		if comment == "" {
+			comment = fmt.Sprintf("timestamp:%d\tfile:%s", time.Now().Unix(), filepath.Base(el))
+		}
+
+		if err := ioutil.WriteFile(el+data.SignatureExt, sk.Sign(content, comment), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("%s signed with key %s\n", el, sk.Public().KeyID())
+	}
+
+	return nil
+}
+
+func (c *SignCmd) defaults() error {
+	if c.SecretKey != "" && (c.PublicKey != "" || !c.Generate) {
+		return nil
+	}
+
+	dir, err := data.DefaultConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if c.SecretKey == "" {
+		c.SecretKey = filepath.Join(dir, "tbd.key")
+	}
+	if c.PublicKey == "" {
+		c.PublicKey = filepath.Join(dir, "tbd.pub")
+	}
+
+	return nil
+}
+
+func (c *SignCmd) generate() error {
+	if _, err := os.Stat(c.SecretKey); err == nil {
+		return fmt.Errorf("secret key '%s' already exists", c.SecretKey)
+	}
+
+	pk, sk, err := data.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.SecretKey), 0700); err != nil {
+		return err
+	}
+	text, _ := sk.MarshalText()
+	if err := ioutil.WriteFile(c.SecretKey, text, 0600); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.PublicKey), 0755); err != nil {
+		return err
+	}
+	text, _ = pk.MarshalText()
+	if err := ioutil.WriteFile(c.PublicKey, text, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("secret key written to %s\npublic key %s written to %s\n", c.SecretKey, pk.KeyID(), c.PublicKey)
+	return nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import "testing"
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want byteSize
+		err  bool
+	}{
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"4KB", 4000, false},
+		{"4 KiB", 4096, false},
+		{"2mb", 2000000, false},
+		{"1GiB", 1 << 30, false},
+		{"9223372036854775807", 9223372036854775807, false},
+		{"9999999999GB", 0, true},
+		{"9007199254740992KiB", 0, true},
+		{"-1KB", 0, true},
+		{"KB", 0, true},
+		{"1TB", 0, true},
+	}
+
+	for _, tc := range tests {
+		var got byteSize
+		err := got.UnmarshalText([]byte(tc.in))
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got [%v] want [%v]", tc.in, got, tc.want)
+		}
+	}
+}
@@ -1,23 +1,74 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
+	"github.com/lucasepe/tbd/pkg/dotenv"
 	"github.com/lucasepe/tbd/pkg/table"
+	"github.com/lucasepe/tbd/pkg/vcs"
 )
 
 type VarsCmd struct {
-	EnvFiles []string `arg:"positional" placeholder:"ENV_FILE"`
+	envOptions
+	fetchOptions
+	repoOptions
+	Origin   bool     `arg:"--origin" help:"shows where every variable has been defined"`
+	JSON     bool     `arg:"--json" help:"prints variables as JSON"`
+	Diagnose bool     `arg:"--diagnose" help:"shows why REPO_* variables cannot be computed"`
+	Snapshot bool     `arg:"--snapshot" help:"saves the REPO_* variables in .tbd-vcs.json at the repository root (for builds without the repository)"`
+	EnvFiles []string `arg:"positional" placeholder:"ENV_FILE" help:"env files to read, or 'set ENV_FILE KEY=VALUE...' and 'unset ENV_FILE KEY...' to edit one"`
+}
+
+type varEntry struct {
+	Name      string          `json:"name"`
+	Value     string          `json:"value"`
+	Origin    *dotenv.Origin  `json:"origin,omitempty"`
+	Overrides []dotenv.Origin `json:"overrides,omitempty"`
 }
 
 func (c *VarsCmd) Run() error {
-	meta, err := builtinVars()
+	if isVarsEdit(c.EnvFiles) {
+		return runVarsEdit(c.EnvFiles)
+	}
+
+	fetch, err := c.fetch()
+	if err != nil {
+		return err
+	}
+
+	prov := dotenv.Provenance{}
+
+	opts, err := c.vcs()
+	if err != nil {
+		return err
+	}
+
+	providers, err := c.providers(c.Repo)
+	if err != nil {
+		return err
+	}
+
+	meta, warnings, err := builtinVars(prov, opts, providers, c.Repo)
 	if err != nil {
 		return err
 	}
 
-	if err := userVars(meta, c.EnvFiles...); err != nil {
+	if c.Diagnose {
+		return c.diagnose(warnings)
+	}
+	c.report(warnings)
+
+	if c.Snapshot {
+		return c.snapshot(meta)
+	}
+
+	p := c.parser(prov, os.Stderr)
+	if err := userVars(meta, fetch, p, c.EnvFiles...); err != nil {
 		return err
 	}
 
@@ -27,14 +78,125 @@ func (c *VarsCmd) Run() error {
 	}
 	sort.Strings(keys)
 
+	if c.JSON {
+		return c.writeJSON(keys, meta, prov)
+	}
+
 	tbl := &table.TextTable{}
-	tbl.SetHeader("Label", "Value")
+	if c.Origin {
+		tbl.SetHeader("Label", "Value", "Origin")
+	} else {
+		tbl.SetHeader("Label", "Value")
+	}
 
 	for _, k := range keys {
-		tbl.AddRow(k, meta[k])
+		if c.Origin {
+			tbl.AddRow(k, meta[k], describeOrigin(prov, k))
+		} else {
+			tbl.AddRow(k, meta[k])
+		}
 	}
 
 	fmt.Println(tbl.Draw())
 
 	return nil
 }
+
+func (c *VarsCmd) writeJSON(keys []string, meta map[string]string, prov dotenv.Provenance) error {
+	list := make([]varEntry, 0, len(keys))
+	for _, k := range keys {
+		el := varEntry{Name: k, Value: meta[k]}
+		if c.Origin {
+			if o, ok := prov.Origin(k); ok {
+				el.Origin = &o
+			}
+			el.Overrides = prov.Overridden(k)
+		}
+		list = append(list, el)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+// snapshot writes the REPO_* variables to the SnapshotFile
+// at the root of the repository (or in the --repo directory
+// or the working directory if it's not a Git repository).
+func (c *VarsCmd) snapshot(meta map[string]string) error {
+	if meta[vcs.RepoProvider] == "" {
+		return fmt.Errorf("no REPO_* variables to save")
+	}
+
+	dir := c.Repo
+	if dir == "" {
+		dir = "."
+	}
+	if root, err := vcs.DetectGitRoot(dir); err == nil && meta[vcs.RepoProvider] == "git" {
+		dir = root
+	}
+
+	filename := filepath.Join(dir, vcs.SnapshotFile)
+	if err := vcs.NewSnapshot(meta).WriteFile(filename); err != nil {
+		return err
+	}
+
+	fmt.Printf("REPO_* variables saved to '%s'\n", filename)
+	return nil
+}
+
+type diagnostic struct {
+	Topic   string   `json:"topic"`
+	Problem string   `json:"problem"`
+	Vars    []string `json:"variables,omitempty"`
+}
+
+// diagnose prints the warnings about the repository variables.
+func (c *VarsCmd) diagnose(warnings []vcs.Warning) error {
+	if c.JSON {
+		list := make([]diagnostic, 0, len(warnings))
+		for _, el := range warnings {
+			list = append(list, diagnostic{Topic: el.Topic, Problem: el.Err.Error(), Vars: el.Vars})
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+
+	if len(warnings) == 0 {
+		fmt.Println("all the REPO_* variables have been computed")
+		return nil
+	}
+
+	tbl := &table.TextTable{}
+	tbl.SetHeader("Topic", "Problem", "Variables")
+	for _, el := range warnings {
+		tbl.AddRow(el.Topic, el.Err.Error(), strings.Join(el.Vars, ", "))
+	}
+
+	fmt.Println(tbl.Draw())
+
+	return nil
+}
+
+// describeOrigin returns a one line description of where
+// the key has been defined and what it has overridden.
+func describeOrigin(prov dotenv.Provenance, key string) string {
+	o, ok := prov.Origin(key)
+	if !ok {
+		return ""
+	}
+
+	prev := prov.Overridden(key)
+	if len(prev) == 0 {
+		return o.String()
+	}
+
+	all := make([]string, len(prev))
+	for i, el := range prev {
+		all[i] = el.String()
+	}
+
+	return fmt.Sprintf("%s (overrides %s)", o, strings.Join(all, ", "))
+}
@@ -10,4 +10,5 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.7.0
 	github.com/whilp/git-urls v1.0.0
+	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b
 )
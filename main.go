@@ -10,6 +10,6 @@ import (
 func main() {
 	if err := cmd.Run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(-1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
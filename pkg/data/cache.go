@@ -0,0 +1,181 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotCached is returned in offline mode when
+// the requested URL is not in the cache.
+var ErrNotCached = errors.New("not in cache")
+
+// CacheEntry describes a cached URL.
+type CacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+	Size         int64     `json:"size"`
+	// Auth is a digest of the request headers (including the credentials):
+	// the same URL fetched with different credentials is cached separately.
+	Auth string `json:"auth,omitempty"`
+}
+
+// Cache stores the content fetched from remote URLs on disk,
+// so that it can be revalidated or used when offline.
+type Cache struct {
+	// Dir is the directory holding the cached files.
+	Dir string
+}
+
+// DefaultCacheDir returns the directory where tbd
+// caches remote content (inside the user cache dir).
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tbd"), nil
+}
+
+// Get returns the cached entry and content for the specified URL,
+// fetched with the credentials whose digest is auth (see CacheEntry).
+// If not found, the error satisfies errors.Is(err, ErrNotCached).
+func (c *Cache) Get(url, auth string) (*CacheEntry, []byte, error) {
+	meta, err := ioutil.ReadFile(c.path(url, auth, ".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("'%s': %w", url, ErrNotCached)
+		}
+		return nil, nil, err
+	}
+
+	entry := &CacheEntry{}
+	if err := json.Unmarshal(meta, entry); err != nil {
+		return nil, nil, fmt.Errorf("corrupted cache entry for '%s': %w", url, err)
+	}
+
+	data, err := ioutil.ReadFile(c.path(url, auth, ".data"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("'%s': %w", url, ErrNotCached)
+		}
+		return nil, nil, err
+	}
+
+	return entry, data, nil
+}
+
+// Put stores the content of the URL described by entry.
+func (c *Cache) Put(entry CacheEntry, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	entry.Size = int64(len(data))
+	meta, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(c.path(entry.URL, entry.Auth, ".data"), data); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.path(entry.URL, entry.Auth, ".json"), meta)
+}
+
+// List returns all the cached entries sorted by URL.
+func (c *Cache) List() ([]CacheEntry, error) {
+	all, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]CacheEntry, 0, len(all))
+	for _, el := range all {
+		buf, err := ioutil.ReadFile(el)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := CacheEntry{}
+		if err := json.Unmarshal(buf, &entry); err != nil {
+			continue
+		}
+		res = append(res, entry)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].URL < res[j].URL
+	})
+
+	return res, nil
+}
+
+// Clear removes all the cached entries
+// returning how many have been removed.
+func (c *Cache) Clear() (int, error) {
+	all, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	count := 0
+	for _, fi := range all {
+		ext := filepath.Ext(fi.Name())
+		if ext != ".json" && ext != ".data" {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.Dir, fi.Name())); err != nil {
+			return count, err
+		}
+		if ext == ".json" {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (c *Cache) path(url, auth, ext string) string {
+	key := url
+	if auth != "" {
+		key += "\n" + auth
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+ext)
+}
+
+// writeFileAtomic writes data to a temporary file renamed
+// to filename, so that readers never see a partial file.
+func writeFileAtomic(filename string, data []byte) error {
+	fp, err := ioutil.TempFile(filepath.Dir(filename), "."+strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	if err != nil {
+		return err
+	}
+
+	_, err = fp.Write(data)
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fp.Name())
+		return err
+	}
+
+	return os.Rename(fp.Name(), filename)
+}
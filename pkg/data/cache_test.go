@@ -0,0 +1,136 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFetchWithCache(t *testing.T) {
+	var hits, revalidated int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "Hello from cache!")
+	}))
+
+	cache := &Cache{Dir: t.TempDir()}
+	f := &Fetcher{Cache: cache}
+
+	for i := 0; i < 2; i++ {
+		data, err := f.FetchFromURI(ts.URL, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != "Hello from cache!" {
+			t.Errorf("%d: got [%v] want [Hello from cache!]", i, got)
+		}
+	}
+
+	if hits != 2 || revalidated != 1 {
+		t.Errorf("got %d hits and %d revalidations, want 2 and 1", hits, revalidated)
+	}
+
+	// offline mode never hits the server
+	offline := &Fetcher{Cache: cache, Offline: true}
+	if data, err := offline.FetchFromURI(ts.URL, 0); err != nil || string(data) != "Hello from cache!" {
+		t.Errorf("offline: got [%s] err %v", data, err)
+	}
+	if hits != 2 {
+		t.Errorf("offline mode contacted the server")
+	}
+	if _, err := offline.FetchFromURI(ts.URL+"/other", 0); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected not cached error, got %v", err)
+	}
+
+	// stale copy is used, with a warning, when the server is unreachable
+	var warnings []error
+	f.Warn = func(err error) { warnings = append(warnings, err) }
+	ts.Close()
+	if data, err := f.FetchFromURI(ts.URL, 0); err != nil || string(data) != "Hello from cache!" {
+		t.Errorf("server down: got [%s] err %v", data, err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "using the copy of '"+ts.URL+"' cached ") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	list, err := cache.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].URL != ts.URL || list[0].ETag != `"v1"` || list[0].Size != 17 {
+		t.Errorf("unexpected cache entries %+v", list)
+	}
+
+	n, err := cache.Clear()
+	if err != nil || n != 1 {
+		t.Errorf("clear: got %d entries, err %v", n, err)
+	}
+	if list, _ := cache.List(); len(list) != 0 {
+		t.Errorf("cache not empty after clear: %v", list)
+	}
+}
+
+func TestCacheNotFoundIsNotMasked(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, "content")
+	}))
+	defer ts.Close()
+
+	f := &Fetcher{Cache: &Cache{Dir: t.TempDir()}}
+	if _, err := f.FetchFromURI(ts.URL, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	status = http.StatusNotFound
+	var herr *HTTPError
+	if _, err := f.FetchFromURI(ts.URL, 0); !errors.As(err, &herr) || !herr.NotFound() {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	status = http.StatusBadGateway
+	if data, err := f.FetchFromURI(ts.URL, 0); err != nil || string(data) != "content" {
+		t.Errorf("server error: got [%s] err %v", data, err)
+	}
+}
+
+func TestCacheKeyIncludesCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		fmt.Fprintf(w, "hello %s", user)
+	}))
+	defer ts.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	for _, user := range []string{"alice", "bob"} {
+		f := &Fetcher{Cache: cache, Username: user, Password: "secret"}
+		if _, err := f.FetchFromURI(ts.URL, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	anonymous := &Fetcher{Cache: cache, Offline: true}
+	if _, err := anonymous.FetchFromURI(ts.URL, 0); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected not cached error, got %v", err)
+	}
+
+	bob := &Fetcher{Cache: cache, Offline: true, Username: "bob", Password: "secret"}
+	if data, err := bob.FetchFromURI(ts.URL, 0); err != nil || string(data) != "hello bob" {
+		t.Errorf("got [%s] err %v", data, err)
+	}
+
+	if list, _ := cache.List(); len(list) != 2 {
+		t.Errorf("got %d cache entries want 2", len(list))
+	}
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strings"
+)
+
+// ErrChecksumMismatch is reported (wrapped in a *ChecksumError)
+// when the fetched data does not match the pinned digest.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumError is returned when the data at URI
+// does not match the expected digest.
+type ChecksumError struct {
+	URI       string
+	Algorithm string
+	Want      string
+	Got       string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch for '%s': want %s, got %s",
+		e.Algorithm, e.URI, e.Want, e.Got)
+}
+
+// Is makes errors.Is(err, ErrChecksumMismatch) work.
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+var hashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// checksum is the expected digest of some data.
+type checksum struct {
+	algorithm string
+	digest    string
+}
+
+var checksumFragmentRegex = regexp.MustCompile(`#(sha256|sha384|sha512)=([0-9a-fA-F]+)$`)
+
+// splitChecksum removes from uri the optional '#<algorithm>=<hex>'
+// fragment, reporting a digest not as long as the algorithm requires.
+func splitChecksum(uri string) (string, *checksum, error) {
+	m := checksumFragmentRegex.FindStringSubmatchIndex(uri)
+	if m == nil {
+		return uri, nil, nil
+	}
+
+	sum := &checksum{
+		algorithm: uri[m[2]:m[3]],
+		digest:    strings.ToLower(uri[m[4]:m[5]]),
+	}
+	if size := hashes[sum.algorithm]().Size() * 2; len(sum.digest) != size {
+		return "", nil, fmt.Errorf("malformed %s digest in '%s': expected %d hex digits, got %d",
+			sum.algorithm, uri, size, len(sum.digest))
+	}
+
+	return uri[:m[0]], sum, nil
+}
+
+// verify checks that data matches the expected digest.
+func (c *checksum) verify(uri string, data []byte) error {
+	h := hashes[c.algorithm]()
+	h.Write(data)
+
+	got := hex.EncodeToString(h.Sum(nil))
+	if got != c.digest {
+		return &ChecksumError{URI: uri, Algorithm: c.algorithm, Want: c.digest, Got: got}
+	}
+
+	return nil
+}
+
+// WithChecksum pins uri to the specified digest (i.e. 'sha256:<hex>'),
+// so that fetching it fails if the content does not match.
+func WithChecksum(uri, sum string) (string, error) {
+	idx := strings.IndexAny(sum, ":=")
+	if idx == -1 {
+		return "", fmt.Errorf("invalid checksum '%s', expected <algorithm>:<hex>", sum)
+	}
+
+	algorithm, digest := strings.ToLower(sum[:idx]), strings.ToLower(sum[idx+1:])
+	fn, ok := hashes[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported checksum algorithm '%s'", algorithm)
+	}
+
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != fn().Size() {
+		return "", fmt.Errorf("invalid %s digest '%s'", algorithm, digest)
+	}
+
+	base, pinned, err := splitChecksum(uri)
+	if err != nil {
+		return "", err
+	}
+	if pinned != nil && (pinned.algorithm != algorithm || pinned.digest != digest) {
+		return "", fmt.Errorf("'%s' is already pinned to a different checksum", uri)
+	}
+
+	return fmt.Sprintf("%s#%s=%s", base, algorithm, digest), nil
+}
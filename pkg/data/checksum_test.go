@@ -0,0 +1,93 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a well formed sha256 digest of some other content
+const otherDigest = "a2b5bd5d5f0eb4d0b7fd90d1b7c6a4bd4a5d4f1b2bba01eb0a4fe50e44a24bd4"
+
+func TestFetchChecksum(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Fragment != "" {
+			t.Errorf("fragment sent to the server: %s", r.URL.Fragment)
+		}
+		fmt.Fprint(w, "Hello from scrawl!")
+	}))
+	defer ts.Close()
+
+	sum := checksum{algorithm: "sha256"}
+	h := hashes["sha256"]()
+	h.Write([]byte("Hello from scrawl!"))
+	sum.digest = fmt.Sprintf("%x", h.Sum(nil))
+
+	data, err := Fetch(ts.URL+"/t.tbd#sha256="+sum.digest, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "Hello from scrawl!" {
+		t.Errorf("got [%v] want [Hello from scrawl!]", got)
+	}
+
+	_, err = Fetch(ts.URL+"/t.tbd#sha256="+otherDigest, 0)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+
+	var cerr *ChecksumError
+	if !errors.As(err, &cerr) || cerr.Got != sum.digest || cerr.URI != ts.URL+"/t.tbd" {
+		t.Errorf("unexpected error details %v", err)
+	}
+
+	_, err = Fetch(ts.URL+"/t.tbd#sha256=ab", 0)
+	if err == nil || errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "malformed sha256 digest") {
+		t.Errorf("expected malformed digest, got %v", err)
+	}
+
+	uri, err := WithChecksum(ts.URL+"/t.tbd", "SHA256:"+sum.digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Fetch(uri, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithChecksum(t *testing.T) {
+	tests := []struct {
+		uri  string
+		sum  string
+		want string
+		err  bool
+	}{
+		{"t.tbd", "sha256:" + otherDigest, "t.tbd#sha256=" + otherDigest, false},
+		{"t.tbd", "sha256=" + otherDigest, "t.tbd#sha256=" + otherDigest, false},
+		{"t.tbd#sha256=" + otherDigest, "sha256:" + otherDigest, "t.tbd#sha256=" + otherDigest, false},
+		{"t.tbd#sha256=" + otherDigest[1:] + "0", "sha256:" + otherDigest, "", true},
+		{"t.tbd", "md5:d41d8cd98f00b204e9800998ecf8427e", "", true},
+		{"t.tbd", "sha256:abc", "", true},
+		{"t.tbd", otherDigest, "", true},
+		{"t.tbd#sha512=" + otherDigest, "sha256:" + otherDigest, "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := WithChecksum(tc.uri, tc.sum)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s %s: expected error", tc.uri, tc.sum)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", tc.uri, tc.sum, err)
+		}
+		if got != tc.want {
+			t.Errorf("got [%v] want [%v]", got, tc.want)
+		}
+	}
+}
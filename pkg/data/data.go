@@ -1,55 +1,435 @@
 package data
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"strings"
+	"sort"
+	"time"
 )
 
 // Fetch gets the bytes at the specified URI.
-// The URI can be remote (http) or local.
-// if 'limit' is greater then zero, fetch stops
-// with EOF after 'limit' bytes.
+// The URI can be remote (http) or local (see Fetcher.Fetch).
+// if 'limit' is greater then zero and the data is larger,
+// only the first 'limit' bytes are returned along with a *LimitError.
 func Fetch(uri string, limit int64) ([]byte, error) {
-	if strings.HasPrefix(uri, "http") {
-		return FetchFromURI(uri, limit)
-	}
-
-	return FetchFromFile(uri, limit)
+	f := &Fetcher{}
+	return f.Fetch(uri, limit)
 }
 
 // FetchFromURI fetch data (with limit) from an HTTP URL.
-// if 'limit' is greater then zero, fetch stops
-// with EOF after 'limit' bytes.
+// Non-2xx responses are reported as *HTTPError.
+// if 'limit' is greater then zero and the data is larger,
+// only the first 'limit' bytes are returned along with a *LimitError.
 func FetchFromURI(uri string, limit int64) ([]byte, error) {
-	res, err := http.Get(uri)
+	f := &Fetcher{}
+	return f.FetchFromURI(uri, limit)
+}
+
+// FetchFromFile fetch data (with limit) from an file.
+// if 'limit' is greater then zero and the data is larger,
+// only the first 'limit' bytes are returned along with a *LimitError.
+func FetchFromFile(filename string, limit int64) ([]byte, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return readLimited(fp, filename, limit)
+}
+
+// readLimited reads at most 'limit' bytes from r (all
+// if 'limit' is not greater than zero), reporting
+// with a *LimitError if there was more to read.
+func readLimited(r io.Reader, uri string, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(r)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
 	if err != nil {
 		return nil, err
 	}
+
+	if int64(len(data)) > limit {
+		return data[:limit], &LimitError{URI: uri, Limit: limit}
+	}
+
+	return data, nil
+}
+
+// Fetcher gets data from local files or URIs.
+// The zero value is ready to use.
+type Fetcher struct {
+	// Schemes are additional (or overridden) URI scheme
+	// handlers, looked up before the registered ones.
+	Schemes map[string]Opener
+	// Client is the HTTP client (http.DefaultClient if nil).
+	Client *http.Client
+	// Header is added to every HTTP request.
+	Header http.Header
+	// Username and Password, if set, are used for HTTP basic authentication.
+	Username string
+	Password string
+	// Netrc, if set, is the .netrc file where to look up
+	// the basic authentication credentials for each host.
+	Netrc string
+	// Timeout limits the duration of each HTTP request (zero means no limit).
+	Timeout time.Duration
+	// Retries is the number of additional attempts made after
+	// a network error or a 429 and 5xx HTTP status code.
+	Retries int
+	// Backoff is the wait before the first retry, doubled at each attempt.
+	Backoff time.Duration
+	// Cache, if not nil, stores the content fetched from HTTP URLs.
+	Cache *Cache
+	// Offline serves HTTP URLs only from the Cache.
+	Offline bool
+	// Verifier, if not nil, checks the signature of all fetched data.
+	Verifier *Verifier
+	// Policy, if not nil, restricts the URIs that can be fetched
+	// (including redirects and signatures).
+	Policy *Policy
+	// Warn, if not nil, receives the warnings (i.e. a stale cached copy
+	// served in place of an unavailable URL); they are printed on stderr otherwise.
+	Warn func(err error)
+}
+
+// Fetch gets the bytes at the specified URI.
+// The URI can be a local path or use any of the supported
+// schemes (file, http, https, data, env, git and registered ones).
+// The URI can be pinned to a digest adding a '#sha256=<hex>'
+// (or sha384, sha512) fragment: data not matching is reported
+// as *ChecksumError.
+// If a Verifier is set, the data must have a valid detached signature
+// (at the same URI plus '.sig'), otherwise a *SignatureError is returned.
+// if 'limit' is greater then zero and the data is larger,
+// only the first 'limit' bytes are returned along with a *LimitError.
+func (f *Fetcher) Fetch(uri string, limit int64) ([]byte, error) {
+	uri, sum, err := splitChecksum(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.fetch(uri, limit)
+	if err != nil {
+		return data, err
+	}
+
+	if sum != nil {
+		if err := sum.verify(uri, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Verifier != nil {
+		if err := f.verify(uri, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// verify checks the detached signature of the data at uri.
+func (f *Fetcher) verify(uri string, data []byte) error {
+	sig, err := f.fetch(signatureURI(uri), maxSignatureSize)
+	if err != nil {
+		return &SignatureError{URI: uri, Reason: fmt.Sprintf("cannot fetch signature: %v", err)}
+	}
+
+	if _, err := f.Verifier.Verify(data, sig); err != nil {
+		return &SignatureError{URI: uri, Reason: err.Error()}
+	}
+
+	return nil
+}
+
+// fetch dispatches the URI to the handler of its scheme.
+func (f *Fetcher) fetch(uri string, limit int64) ([]byte, error) {
+	if f.Policy != nil {
+		if err := f.Policy.Check(uri); err != nil {
+			return nil, err
+		}
+	}
+
+	scheme := schemeOf(uri)
+	if scheme == "" {
+		return FetchFromFile(uri, limit)
+	}
+
+	o, ok := f.Schemes[scheme]
+	if !ok {
+		if scheme == "http" || scheme == "https" {
+			return f.FetchFromURI(uri, limit)
+		}
+
+		o, ok = registered(scheme)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported scheme '%s' in '%s'", scheme, uri)
+	}
+
+	// the repository depends on the opener
+	if g, ok := o.(*GitOpener); ok && f.Policy != nil {
+		if err := f.Policy.checkGit(uri, g); err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := o.Open(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return readLimited(rc, uri, limit)
+}
+
+// FetchFromURI fetch data (with limit) from an HTTP URL,
+// retrying on network errors and temporary failures.
+// Non-2xx responses are reported as *HTTPError.
+// When a Cache is set, cached content is revalidated (and used,
+// with a warning, if the server is unreachable or failing) or,
+// in Offline mode, served without any request.
+// The URL must be allowed by the Policy (if any).
+// if 'limit' is greater then zero and the data is larger,
+// only the first 'limit' bytes are returned along with a *LimitError.
+func (f *Fetcher) FetchFromURI(uri string, limit int64) ([]byte, error) {
+	if f.Policy != nil {
+		if err := f.Policy.Check(uri); err != nil {
+			return nil, err
+		}
+	}
+
+	var entry *CacheEntry
+	var cached []byte
+	auth := f.authKey(uri)
+	if f.Cache != nil {
+		entry, cached, _ = f.Cache.Get(uri, auth)
+	}
+
+	if f.Offline {
+		if f.Cache == nil {
+			return nil, fmt.Errorf("cannot fetch '%s' in offline mode without a cache", uri)
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("cannot fetch '%s' in offline mode: %w", uri, ErrNotCached)
+		}
+		return readLimited(bytes.NewReader(cached), uri, limit)
+	}
+
+	wait := f.Backoff
+	for attempt := 0; ; attempt++ {
+		res, retry, err := f.get(uri, limit, entry)
+		if err == nil {
+			return f.store(uri, auth, res, entry, cached, limit)
+		}
+
+		if !retry || attempt >= f.Retries {
+			if entry != nil && isUnavailable(err) {
+				// the server is unreachable or failing: serve the stale copy
+				age := time.Since(entry.Fetched).Round(time.Second)
+				f.warn(fmt.Errorf("using the copy of '%s' cached %s ago: %w", redact(uri), age, err))
+				return readLimited(bytes.NewReader(cached), uri, limit)
+			}
+			if errors.Is(err, ErrLimitExceeded) {
+				return res.data, err
+			}
+			return nil, err
+		}
+
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// response is the outcome of a successful HTTP request.
+type response struct {
+	data         []byte
+	notModified  bool
+	etag         string
+	lastModified string
+}
+
+// store updates the cache (if any) with the response,
+// returning the data to use.
+func (f *Fetcher) store(uri, auth string, res *response, entry *CacheEntry, cached []byte, limit int64) ([]byte, error) {
+	if res.notModified && entry != nil {
+		return readLimited(bytes.NewReader(cached), uri, limit)
+	}
+
+	if f.Cache != nil {
+		// a failure here just means the next fetch will download again
+		f.Cache.Put(CacheEntry{
+			URL:          uri,
+			ETag:         res.etag,
+			LastModified: res.lastModified,
+			Fetched:      time.Now().UTC(),
+			Auth:         auth,
+		}, res.data)
+	}
+
+	return res.data, nil
+}
+
+// warn reports err to Warn or, if nil, on stderr.
+func (f *Fetcher) warn(err error) {
+	if f.Warn != nil {
+		f.Warn(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+}
+
+// authKey returns a digest of the headers sent to uri, credentials
+// included (empty if there are none), which is part of the cache key.
+func (f *Fetcher) authKey(uri string) string {
+	req, err := f.newRequest(context.Background(), uri)
+	if err != nil || len(req.Header) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			fmt.Fprintf(h, "%s: %s\n", k, v)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// isUnavailable reports whether err is a network error or a server error.
+func isUnavailable(err error) bool {
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr.ServerError()
+	}
+	return !errors.Is(err, ErrLimitExceeded)
+}
+
+// get performs a single HTTP request (conditional if entry is not nil)
+// reporting whether it is worth to be retried.
+func (f *Fetcher) get(uri string, limit int64, entry *CacheEntry) (*response, bool, error) {
+	ctx := context.Background()
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := f.newRequest(ctx, uri)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := f.client().Do(req)
+	if err != nil {
+		return nil, !errors.Is(err, ErrForbidden), err
+	}
 	defer res.Body.Close()
 
-	if limit > 0 {
-		return ioutil.ReadAll(io.LimitReader(res.Body, limit))
+	if res.StatusCode == http.StatusNotModified && entry != nil {
+		return &response{notModified: true}, false, nil
+	}
+
+	data, err := readLimited(res.Body, uri, limit)
+	if err != nil && !errors.Is(err, ErrLimitExceeded) {
+		return nil, true, fmt.Errorf("reading '%s': %w", uri, err)
 	}
 
-	return ioutil.ReadAll(res.Body)
+	retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, retry, &HTTPError{
+			URL:        uri,
+			StatusCode: res.StatusCode,
+			Excerpt:    excerpt(data),
+		}
+	}
+
+	return &response{
+		data:         data,
+		etag:         res.Header.Get("ETag"),
+		lastModified: res.Header.Get("Last-Modified"),
+	}, false, err
 }
 
-// FetchFromFile fetch data (with limit) from an file.
-// if 'limit' is greater then zero, fetch stops
-// with EOF after 'limit' bytes.
-func FetchFromFile(filename string, limit int64) ([]byte, error) {
-	fp, err := os.Open(filename)
+// client returns the HTTP client, checking
+// also the redirects against the Policy.
+func (f *Fetcher) client() *http.Client {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if f.Policy == nil {
+		return client
+	}
+
+	res := *client
+	res.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := f.Policy.Check(req.URL.String()); err != nil {
+			return err
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	return &res
+}
+
+func (f *Fetcher) newRequest(ctx context.Context, uri string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer fp.Close()
 
-	if limit > 0 {
-		return ioutil.ReadAll(io.LimitReader(fp, limit))
+	for k, values := range f.Header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	if req.Header.Get("Authorization") != "" {
+		return req, nil
+	}
+
+	if f.Username != "" || f.Password != "" {
+		req.SetBasicAuth(f.Username, f.Password)
+	} else if f.Netrc != "" {
+		if login, password, ok := netrcLookup(f.Netrc, req.URL.Hostname()); ok {
+			req.SetBasicAuth(login, password)
+		}
 	}
 
-	return ioutil.ReadAll(fp)
+	return req, nil
 }
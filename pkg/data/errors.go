@@ -0,0 +1,94 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExcerptSize is the maximum length of the response
+// body reported by an HTTPError.
+const maxExcerptSize = 256
+
+// ErrLimitExceeded is reported (wrapped in a *LimitError)
+// when the data is larger than the requested limit.
+var ErrLimitExceeded = errors.New("size limit exceeded")
+
+// LimitError is returned when the data at URI is larger than Limit bytes.
+type LimitError struct {
+	URI   string
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("'%s' exceeds the maximum size of %d bytes", e.URI, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrLimitExceeded) work.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// HTTPError is returned when a server replies
+// with a non-2xx status code.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	// Excerpt is the beginning of the response body.
+	Excerpt string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("GET %s: %d %s", redact(e.URL), e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Excerpt != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Excerpt)
+	}
+	return msg
+}
+
+// NotFound reports whether the resource does not exist.
+func (e *HTTPError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+}
+
+// ServerError reports whether the server failed to fulfill the request.
+func (e *HTTPError) ServerError() bool {
+	return e.StatusCode >= 500
+}
+
+// excerpt returns the body beginning on a single line.
+func excerpt(body []byte) string {
+	truncated := len(body) > maxExcerptSize
+	if truncated {
+		body = body[:maxExcerptSize]
+		// don't split a multi-byte character
+		for i := len(body) - 1; i >= 0 && i > len(body)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(body[i]) {
+				if !utf8.FullRune(body[i:]) {
+					body = body[:i]
+				}
+				break
+			}
+		}
+	}
+
+	res := strings.Join(strings.Fields(string(body)), " ")
+	if truncated {
+		res = res + "..."
+	}
+	return res
+}
+
+// redact hides the credentials (if any) embedded in an URL.
+func redact(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.User == nil {
+		return uri
+	}
+
+	u.User = url.User("xxxxx")
+	return u.String()
+}
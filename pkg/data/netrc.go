@@ -0,0 +1,109 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NetrcPath returns the location of the user .netrc file
+// ($NETRC if defined).
+func NetrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+type netrcEntry struct {
+	machine  string
+	login    string
+	password string
+}
+
+// netrcLookup returns the credentials defined in the specified
+// .netrc file for host (or the 'default' ones).
+func netrcLookup(filename, host string) (login, password string, ok bool) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", "", false
+	}
+
+	var def *netrcEntry
+	for _, el := range parseNetrc(string(buf)) {
+		if el.machine == host {
+			return el.login, el.password, true
+		}
+		if el.machine == "" && def == nil {
+			e := el
+			def = &e
+		}
+	}
+
+	if def != nil {
+		return def.login, def.password, true
+	}
+
+	return "", "", false
+}
+
+func parseNetrc(data string) []netrcEntry {
+	var res []netrcEntry
+	var cur *netrcEntry
+
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			// a macro definition ends with an empty line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		for i := 0; i < len(tokens); i++ {
+			switch tokens[i] {
+			case "machine", "default":
+				if cur != nil {
+					res = append(res, *cur)
+				}
+				cur = &netrcEntry{}
+				if tokens[i] == "machine" && i+1 < len(tokens) {
+					i++
+					cur.machine = tokens[i]
+				}
+			case "login":
+				if cur != nil && i+1 < len(tokens) {
+					i++
+					cur.login = tokens[i]
+				}
+			case "password":
+				if cur != nil && i+1 < len(tokens) {
+					i++
+					cur.password = tokens[i]
+				}
+			case "account":
+				i++
+			case "macdef":
+				inMacro = true
+				i = len(tokens)
+			}
+		}
+	}
+
+	if cur != nil {
+		res = append(res, *cur)
+	}
+
+	return res
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	src := `machine git.example.com login alice password s3cr3t
+macdef init
+	cd /pub
+	bin
+
+machine api.example.com
+	login bob
+	account ignored
+	password builder
+default login anonymous password guest
+`
+	got := parseNetrc(src)
+
+	want := []netrcEntry{
+		{machine: "git.example.com", login: "alice", password: "s3cr3t"},
+		{machine: "api.example.com", login: "bob", password: "builder"},
+		{machine: "", login: "anonymous", password: "guest"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: got [%v] want [%v]", i, got[i], want[i])
+		}
+	}
+}
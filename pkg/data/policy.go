@@ -0,0 +1,194 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrForbidden is reported (wrapped in a *PolicyError)
+// when an URI is not allowed by the Fetcher Policy.
+var ErrForbidden = errors.New("forbidden by policy")
+
+// PolicyError is returned when an URI violates the Policy.
+type PolicyError struct {
+	URI    string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("'%s' is not allowed: %s", e.URI, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrForbidden) work.
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// localSchemes are the built-in schemes not reaching the network.
+var localSchemes = map[string]bool{
+	"":     true,
+	"file": true,
+	"data": true,
+	"env":  true,
+	"git":  true,
+}
+
+// Policy restricts what a Fetcher can access.
+type Policy struct {
+	// NoRemote forbids any URI reaching the network
+	// (http, https and all the registered schemes).
+	NoRemote bool
+	// AllowedHosts, if not empty, are the only hosts remote URIs may
+	// point to; '*.example.com' matches any subdomain of example.com.
+	AllowedHosts []string
+	// Root, if not empty, is the directory local files
+	// (and the repository read by git URIs) must be in.
+	Root string
+	// AllowedEnv are the only environment variables env URIs may
+	// read ('TBD_*' matches any prefixed one); without them env
+	// URIs are forbidden as soon as any other restriction is set.
+	AllowedEnv []string
+}
+
+// restrictive reports whether any restriction is set.
+func (p *Policy) restrictive() bool {
+	return p.NoRemote || len(p.AllowedHosts) > 0 || p.Root != ""
+}
+
+// Check reports with a *PolicyError if uri is not allowed. The
+// repository read by git URIs is checked by the Fetcher, which
+// knows the GitOpener in use.
+func (p *Policy) Check(uri string) error {
+	scheme := schemeOf(uri)
+
+	if !localSchemes[scheme] {
+		return p.checkRemote(uri)
+	}
+
+	if scheme == "env" {
+		return p.checkEnv(uri)
+	}
+
+	if p.Root == "" {
+		return nil
+	}
+
+	switch scheme {
+	case "":
+		return p.checkPath(uri, uri)
+	case "file":
+		filename, err := FilePath(uri)
+		if err != nil {
+			return &PolicyError{URI: uri, Reason: err.Error()}
+		}
+		return p.checkPath(uri, filename)
+	}
+
+	return nil
+}
+
+func (p *Policy) checkEnv(uri string) error {
+	if !p.restrictive() && len(p.AllowedEnv) == 0 {
+		return nil
+	}
+
+	name := trimScheme(uri)
+	for _, el := range p.AllowedEnv {
+		if ok, _ := path.Match(el, name); ok {
+			return nil
+		}
+	}
+
+	return &PolicyError{URI: uri, Reason: fmt.Sprintf("environment variable '%s' is not in the allowed list", name)}
+}
+
+// checkGit checks that the repository read by g is inside the Root.
+func (p *Policy) checkGit(uri string, g *GitOpener) error {
+	if p.Root == "" {
+		return nil
+	}
+
+	root, err := g.root()
+	if err != nil {
+		return &PolicyError{URI: uri, Reason: err.Error()}
+	}
+
+	return p.checkPath(uri, root)
+}
+
+func (p *Policy) checkRemote(uri string) error {
+	if p.NoRemote {
+		return &PolicyError{URI: uri, Reason: "remote fetching is disabled"}
+	}
+
+	if len(p.AllowedHosts) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return &PolicyError{URI: uri, Reason: err.Error()}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, el := range p.AllowedHosts {
+		if ok, _ := path.Match(strings.ToLower(el), host); ok {
+			return nil
+		}
+	}
+
+	return &PolicyError{URI: uri, Reason: fmt.Sprintf("host '%s' is not in the allowed list", host)}
+}
+
+func (p *Policy) checkPath(uri, filename string) error {
+	root, err := realPath(p.Root)
+	if err != nil {
+		return &PolicyError{URI: uri, Reason: err.Error()}
+	}
+
+	target, err := realPath(filename)
+	if err != nil {
+		return &PolicyError{URI: uri, Reason: err.Error()}
+	}
+
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return &PolicyError{URI: uri, Reason: fmt.Sprintf("outside of '%s'", p.Root)}
+	}
+
+	return nil
+}
+
+// realPath returns the absolute path of filename with all
+// symbolic links resolved (as far as the path exists).
+func realPath(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := filepath.EvalSymlinks(abs)
+	if err == nil {
+		return res, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	// resolve the existing parent
+	dir, file := filepath.Split(abs)
+	if dir == abs || file == "" {
+		return abs, nil
+	}
+
+	parent, err := realPath(filepath.Clean(dir))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, file), nil
+}
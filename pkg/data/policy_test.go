@@ -0,0 +1,140 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyRemote(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		uri    string
+		err    bool
+	}{
+		{Policy{}, "https://example.com/t.tbd", false},
+		{Policy{NoRemote: true}, "https://example.com/t.tbd", true},
+		{Policy{NoRemote: true}, "s3://bucket/t.tbd", true},
+		{Policy{NoRemote: true}, "data:,hello", false},
+		{Policy{}, "env://GITHUB_TOKEN", false},
+		{Policy{NoRemote: true}, "env://GITHUB_TOKEN", true},
+		{Policy{AllowedHosts: []string{"example.com"}}, "env://GITHUB_TOKEN", true},
+		{Policy{Root: "/srv"}, "env://GITHUB_TOKEN", true},
+		{Policy{NoRemote: true, AllowedEnv: []string{"TBD_*"}}, "env://TBD_TEMPLATE", false},
+		{Policy{NoRemote: true, AllowedEnv: []string{"TBD_*"}}, "env://GITHUB_TOKEN", true},
+		{Policy{AllowedEnv: []string{"TBD_TEMPLATE"}}, "env://GITHUB_TOKEN", true},
+		{Policy{NoRemote: true}, "t.tbd", false},
+		{Policy{AllowedHosts: []string{"example.com"}}, "https://example.com/t.tbd", false},
+		{Policy{AllowedHosts: []string{"example.com"}}, "https://EXAMPLE.com:8443/t.tbd", false},
+		{Policy{AllowedHosts: []string{"example.com"}}, "https://evil.com/t.tbd", true},
+		{Policy{AllowedHosts: []string{"example.com"}}, "https://git.example.com/t.tbd", true},
+		{Policy{AllowedHosts: []string{"*.example.com"}}, "https://git.example.com/t.tbd", false},
+		{Policy{AllowedHosts: []string{"*.example.com"}}, "https://example.com.evil.com/t.tbd", true},
+	}
+
+	for _, tc := range tests {
+		err := tc.policy.Check(tc.uri)
+		if tc.err != (err != nil) {
+			t.Errorf("%+v %s: unexpected result %v", tc.policy, tc.uri, err)
+		}
+		if err != nil && !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s: expected ErrForbidden, got %v", tc.uri, err)
+		}
+	}
+}
+
+func TestPolicyRoot(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	if err := os.MkdirAll(filepath.Join(root, "tpl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(base, "secret.txt"), []byte("s3cr3t"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "tpl", "pod.tbd"), []byte("{{ name }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	f := &Fetcher{Policy: &Policy{Root: root}}
+
+	tests := []struct {
+		uri string
+		err bool
+	}{
+		{filepath.Join(root, "tpl", "pod.tbd"), false},
+		{"file://" + filepath.ToSlash(filepath.Join(root, "tpl", "pod.tbd")), false},
+		{filepath.Join(root, "tpl", "..", "..", "secret.txt"), true},
+		{filepath.Join(base, "secret.txt"), true},
+		{"file://" + filepath.ToSlash(filepath.Join(base, "secret.txt")), true},
+		{filepath.Join(root, "link.txt"), true},
+		{filepath.Join(root, "missing", "file.tbd"), false},
+		{"file://localhost" + filepath.ToSlash(filepath.Join(root, "tpl", "pod.tbd")), false},
+		// a host would be opened as a relative path
+		{"file://h" + filepath.ToSlash(filepath.Join(root, "tpl", "pod.tbd")), true},
+		{"file:../secret.txt", true},
+	}
+
+	for _, tc := range tests {
+		_, err := f.Fetch(tc.uri, 0)
+		forbidden := errors.Is(err, ErrForbidden)
+		if forbidden != tc.err {
+			t.Errorf("%s: unexpected result %v", tc.uri, err)
+		}
+	}
+}
+
+func TestPolicyRedirect(t *testing.T) {
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "evil")
+	}))
+	defer evil.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, evil.URL+"/t.tbd", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	// both servers listen on 127.0.0.1: tell them apart by name
+	f := &Fetcher{Policy: &Policy{AllowedHosts: []string{"127.0.0.1"}}}
+	if _, err := f.Fetch(ts.URL, 0); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	evilURL := "http://localhost:" + evil.URL[len("http://127.0.0.1:"):]
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, evilURL+"/t.tbd", http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	if _, err := f.Fetch(redirect.URL, 0); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected forbidden redirect, got %v", err)
+	}
+}
+
+func TestPolicyFetchFromURI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer ts.Close()
+
+	for _, p := range []*Policy{{NoRemote: true}, {AllowedHosts: []string{"example.com"}}} {
+		f := &Fetcher{Policy: p}
+		if _, err := f.FetchFromURI(ts.URL, 0); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%+v: expected ErrForbidden, got %v", p, err)
+		}
+	}
+
+	f := &Fetcher{Policy: &Policy{AllowedHosts: []string{"127.0.0.1"}}}
+	if _, err := f.FetchFromURI(ts.URL, 0); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package data
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/lucasepe/tbd/pkg/vcs"
+)
+
+// Opener opens the resource identified by an URI.
+type Opener interface {
+	Open(uri string) (io.ReadCloser, error)
+}
+
+// OpenerFunc is an adapter to use ordinary functions as Opener.
+type OpenerFunc func(uri string) (io.ReadCloser, error)
+
+// Open calls fn(uri).
+func (fn OpenerFunc) Open(uri string) (io.ReadCloser, error) {
+	return fn(uri)
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]Opener{
+		"file": OpenerFunc(openFile),
+		"data": OpenerFunc(openData),
+		"env":  OpenerFunc(openEnv),
+		"git":  &GitOpener{},
+	}
+)
+
+// Register makes an Opener available for the specified URI scheme.
+// The http and https schemes are always handled by the Fetcher.
+func Register(scheme string, o Opener) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[strings.ToLower(scheme)] = o
+}
+
+func registered(scheme string) (Opener, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	o, ok := schemes[scheme]
+	return o, ok
+}
+
+var schemeRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]+):`)
+
+// schemeOf returns the (lower case) scheme of uri, or an empty string
+// if uri is a plain path. A scheme is recognized when followed by '//'
+// or when registered (i.e. 'data:'); single letters are Windows drives.
+func schemeOf(uri string) string {
+	m := schemeRegex.FindStringSubmatch(uri)
+	if m == nil {
+		return ""
+	}
+
+	scheme := strings.ToLower(m[1])
+	if strings.HasPrefix(uri[len(m[0]):], "//") {
+		return scheme
+	}
+
+	if _, ok := registered(scheme); ok {
+		return scheme
+	}
+
+	return ""
+}
+
+// trimScheme removes 'scheme:' and the optional '//' from uri.
+func trimScheme(uri string) string {
+	if idx := strings.Index(uri, ":"); idx != -1 {
+		uri = uri[idx+1:]
+	}
+	return strings.TrimPrefix(uri, "//")
+}
+
+// openFile handles 'file:///path/to/file' URIs.
+func openFile(uri string) (io.ReadCloser, error) {
+	path, err := FilePath(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// LocalPath returns the path of a local template or env file: a plain
+// path or a file URI, optionally pinned to a digest ('#sha256=<hex>').
+func LocalPath(uri string) (string, error) {
+	if m := checksumFragmentRegex.FindStringIndex(uri); m != nil {
+		uri = uri[:m[0]]
+	}
+
+	switch scheme := schemeOf(uri); scheme {
+	case "":
+		return uri, nil
+	case "file":
+		return FilePath(uri)
+	default:
+		return "", fmt.Errorf("'%s' is not a local file", uri)
+	}
+}
+
+// FilePath returns the local path of a 'file:///path/to/file' URI.
+// Only the empty and the 'localhost' hosts are accepted.
+func FilePath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.EqualFold(u.Scheme, "file") {
+		return "", fmt.Errorf("'%s' is not a file URI", uri)
+	}
+	if u.Opaque != "" {
+		return "", fmt.Errorf("invalid file URI '%s', expected file:///path", uri)
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return "", fmt.Errorf("invalid file URI '%s': host '%s' is not local", uri, u.Host)
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("invalid file URI '%s': missing path", uri)
+	}
+
+	path := u.Path
+	// '/C:/path' on Windows
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path), nil
+}
+
+// openData handles RFC 2397 'data:[<mediatype>][;base64],<data>' URIs.
+func openData(uri string) (io.ReadCloser, error) {
+	content := trimScheme(uri)
+
+	idx := strings.Index(content, ",")
+	if idx == -1 {
+		return nil, fmt.Errorf("invalid data URI: missing ','")
+	}
+
+	meta, payload := content[:idx], content[idx+1:]
+
+	var buf []byte
+	if strings.HasSuffix(meta, ";base64") {
+		dec, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			dec, err = base64.URLEncoding.DecodeString(payload)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URI: %w", err)
+		}
+		buf = dec
+	} else {
+		dec, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URI: %w", err)
+		}
+		buf = []byte(dec)
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(buf)), nil
+}
+
+// openEnv handles 'env://VAR' URIs returning the
+// content of the specified environment variable.
+func openEnv(uri string) (io.ReadCloser, error) {
+	name := trimScheme(uri)
+
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable '%s' is not set", name)
+	}
+
+	return ioutil.NopCloser(strings.NewReader(val)), nil
+}
+
+// GitOpener handles 'git://<ref>:<path>' URIs reading the file
+// at path, as stored in the specified ref, from a local repository.
+type GitOpener struct {
+	// Path is a directory inside the repository
+	// (the current working directory if empty).
+	Path string
+}
+
+// Open reads the file from the repository.
+func (g *GitOpener) Open(uri string) (io.ReadCloser, error) {
+	spec := trimScheme(uri)
+
+	idx := strings.Index(spec, ":")
+	if idx <= 0 || idx == len(spec)-1 {
+		return nil, fmt.Errorf("invalid git URI '%s': expected git://<ref>:<path>", uri)
+	}
+
+	path, err := g.path()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := vcs.OpenGitRepo(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return vcs.FileFromGitRepo(repo, spec[:idx], spec[idx+1:])
+}
+
+// path returns the Path or the current working directory.
+func (g *GitOpener) path() (string, error) {
+	if g.Path != "" {
+		return g.Path, nil
+	}
+	return os.Getwd()
+}
+
+// root returns the root of the repository read by g.
+func (g *GitOpener) root() (string, error) {
+	path, err := g.path()
+	if err != nil {
+		return "", err
+	}
+	return vcs.DetectGitRoot(path)
+}
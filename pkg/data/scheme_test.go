@@ -0,0 +1,236 @@
+package data
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestSchemeOf(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"httpd.conf.tbd", ""},
+		{"../testdata/sample.tbd", ""},
+		{`C:\templates\pod.tbd`, ""},
+		{"notes:draft.tbd", ""},
+		{"http://example.com/t.tbd", "http"},
+		{"HTTPS://example.com/t.tbd", "https"},
+		{"file:///tmp/t.tbd", "file"},
+		{"data:,hello", "data"},
+		{"env://TEMPLATE", "env"},
+		{"git://main:deploy/pod.tbd", "git"},
+		{"s3://bucket/t.tbd", "s3"},
+	}
+
+	for _, tc := range tests {
+		if got := schemeOf(tc.uri); got != tc.want {
+			t.Errorf("%s: got [%v] want [%v]", tc.uri, got, tc.want)
+		}
+	}
+}
+
+func TestFetchSchemes(t *testing.T) {
+	os.Setenv("TBD_TEST_TEMPLATE", "{{ from.env }}")
+	defer os.Unsetenv("TBD_TEST_TEMPLATE")
+
+	abs, err := filepath.Abs("../../testdata/sample.vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"data:,Hello%2C%20World", "Hello, World"},
+		{"data:text/plain;base64,SGVsbG8sIFdvcmxk", "Hello, World"},
+		{"env://TBD_TEST_TEMPLATE", "{{ from.env }}"},
+		{"file://" + filepath.ToSlash(abs), "greeting: Greetings"},
+	}
+
+	for _, tc := range tests {
+		data, err := Fetch(tc.uri, 0)
+		if err != nil {
+			t.Errorf("%s: %v", tc.uri, err)
+			continue
+		}
+		if got := string(data); !strings.HasPrefix(got, tc.want) {
+			t.Errorf("%s: got [%v] want [%v]", tc.uri, got, tc.want)
+		}
+	}
+
+	for _, uri := range []string{"env://TBD_TEST_MISSING", "ftp://example.com/t.tbd", "data:nocomma", "file://h/tmp/t.tbd", "file:t.tbd"} {
+		if _, err := Fetch(uri, 0); err == nil {
+			t.Errorf("%s: expected error", uri)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("mem", OpenerFunc(func(uri string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("from " + uri)), nil
+	}))
+
+	data, err := Fetch("mem://templates/pod.tbd", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "from mem://templates/pod.tbd"; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+
+	f := &Fetcher{
+		Schemes: map[string]Opener{
+			"mem": OpenerFunc(func(uri string) (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader("overridden")), nil
+			}),
+		},
+	}
+	data, err = f.Fetch("mem://templates/pod.tbd", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "overridden" {
+		t.Errorf("got [%v] want [overridden]", got)
+	}
+}
+
+func TestGitOpener(t *testing.T) {
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(content string) {
+		if err := os.MkdirAll(filepath.Join(dir, "deploy"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		err := ioutil.WriteFile(filepath.Join(dir, "deploy", "pod.tbd"), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("deploy/pod.tbd"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		if _, err := wt.Commit(content, &git.CommitOptions{Author: sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit("v1")
+	commit("v2")
+
+	g := &GitOpener{Path: dir}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"git://HEAD:deploy/pod.tbd", "v2"},
+		{"git://HEAD~1:deploy/pod.tbd", "v1"},
+		{"git://master:/deploy/pod.tbd", "v2"},
+	}
+
+	for _, tc := range tests {
+		rc, err := g.Open(tc.uri)
+		if err != nil {
+			t.Errorf("%s: %v", tc.uri, err)
+			continue
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+
+		if got := string(data); got != tc.want {
+			t.Errorf("%s: got [%v] want [%v]", tc.uri, got, tc.want)
+		}
+	}
+
+	if _, err := g.Open("git://HEAD:missing.tbd"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+	if _, err := g.Open("git://HEAD"); err == nil {
+		t.Errorf("expected error for invalid URI")
+	}
+
+	// the repository must be inside the root
+	f := &Fetcher{Schemes: map[string]Opener{"git": g}, Policy: &Policy{Root: dir}}
+	if _, err := f.Fetch("git://HEAD:deploy/pod.tbd", 0); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	f.Policy.Root = filepath.Join(dir, "deploy")
+	if _, err := f.Fetch("git://HEAD:deploy/pod.tbd", 0); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+		err  bool
+	}{
+		{"file:///tmp/t.tbd", "/tmp/t.tbd", false},
+		{"file://localhost/tmp/t.tbd", "/tmp/t.tbd", false},
+		{"file:///tmp/my%20t.tbd", "/tmp/my t.tbd", false},
+		{"file://h/tmp/t.tbd", "", true},
+		{"file:t.tbd", "", true},
+		{"file://", "", true},
+		{"https://example.com/t.tbd", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := FilePath(tc.uri)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.uri, err)
+			continue
+		}
+		if got != filepath.FromSlash(tc.want) {
+			t.Errorf("%s: got [%v] want [%v]", tc.uri, got, tc.want)
+		}
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+		err  bool
+	}{
+		{"tpl/pod.tbd", "tpl/pod.tbd", false},
+		{"tpl/#shared/pod.tbd", "tpl/#shared/pod.tbd", false},
+		{"tpl/pod.tbd#sha256=" + otherDigest, "tpl/pod.tbd", false},
+		{"file:///tmp/pod.tbd", "/tmp/pod.tbd", false},
+		{"file://h/tmp/pod.tbd", "", true},
+		{"https://example.com/pod.tbd", "", true},
+		{"env://TBD_TEMPLATE", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := LocalPath(tc.uri)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error %v", tc.uri, err)
+			continue
+		}
+		if got != filepath.FromSlash(tc.want) {
+			t.Errorf("%s: got [%v] want [%v]", tc.uri, got, tc.want)
+		}
+	}
+}
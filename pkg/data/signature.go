@@ -0,0 +1,320 @@
+package data
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Keys and signatures use the minisign format (https://jedisct1.github.io/minisign/),
+// so that they can be created and verified with both tools.
+
+// ErrInvalidSignature is reported (wrapped in a *SignatureError)
+// when the signature of the fetched data cannot be verified.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureError is returned when the signature
+// of the data at URI cannot be verified.
+type SignatureError struct {
+	URI    string
+	Reason string
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("invalid signature for '%s': %s", e.URI, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrInvalidSignature) work.
+func (e *SignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
+
+const (
+	// SignatureExt is appended to the URI of the signed data
+	// to get the URI of its detached signature.
+	SignatureExt = ".sig"
+
+	// maxSignatureSize is the maximum size of a signature file.
+	maxSignatureSize = 4096
+)
+
+var (
+	algEd       = []byte("Ed") // signs the data
+	algEdHashed = []byte("ED") // signs the BLAKE2b-512 hash of the data
+	algChecksum = []byte("B2")
+)
+
+// PublicKey is an ed25519 public key with its 8 bytes identifier.
+type PublicKey struct {
+	ID  [8]byte
+	Key ed25519.PublicKey
+}
+
+// KeyID returns the key identifier as shown by minisign.
+func (pk *PublicKey) KeyID() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(pk.ID[:]))
+}
+
+// MarshalText returns the public key in minisign format.
+func (pk *PublicKey) MarshalText() ([]byte, error) {
+	raw := make([]byte, 0, 42)
+	raw = append(raw, algEd...)
+	raw = append(raw, pk.ID[:]...)
+	raw = append(raw, pk.Key...)
+
+	return []byte(fmt.Sprintf("untrusted comment: minisign public key %s\n%s\n",
+		pk.KeyID(), base64.StdEncoding.EncodeToString(raw))), nil
+}
+
+// ParsePublicKey reads a public key in minisign format
+// (the comment line is optional).
+func ParsePublicKey(text []byte) (*PublicKey, error) {
+	raw, err := decodeKeyLine(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) != 42 || !bytes.Equal(raw[:2], algEd) {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	pk := &PublicKey{Key: ed25519.PublicKey(raw[10:])}
+	copy(pk.ID[:], raw[2:10])
+	return pk, nil
+}
+
+// SecretKey is an ed25519 private key with its 8 bytes identifier.
+type SecretKey struct {
+	ID  [8]byte
+	Key ed25519.PrivateKey
+}
+
+// GenerateKey creates a new key pair.
+func GenerateKey() (*PublicKey, *SecretKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sk := &SecretKey{Key: priv}
+	if _, err := rand.Read(sk.ID[:]); err != nil {
+		return nil, nil, err
+	}
+
+	return &PublicKey{ID: sk.ID, Key: pub}, sk, nil
+}
+
+// Public returns the public key matching sk.
+func (sk *SecretKey) Public() *PublicKey {
+	return &PublicKey{ID: sk.ID, Key: sk.Key.Public().(ed25519.PublicKey)}
+}
+
+// MarshalText returns the secret key in (unencrypted) minisign format.
+func (sk *SecretKey) MarshalText() ([]byte, error) {
+	raw := make([]byte, 158)
+	copy(raw[0:], algEd)
+	// raw[2:4] is the key derivation algorithm: none
+	copy(raw[4:], algChecksum)
+	// raw[6:54] are the key derivation parameters: unused
+	copy(raw[54:], sk.ID[:])
+	copy(raw[62:], sk.Key)
+	copy(raw[126:], sk.checksum())
+
+	return []byte(fmt.Sprintf("untrusted comment: tbd secret key\n%s\n",
+		base64.StdEncoding.EncodeToString(raw))), nil
+}
+
+func (sk *SecretKey) checksum() []byte {
+	h, _ := blake2b.New256(nil)
+	h.Write(algEd)
+	h.Write(sk.ID[:])
+	h.Write(sk.Key)
+	return h.Sum(nil)
+}
+
+// ParseSecretKey reads an unencrypted secret key in minisign format.
+func ParseSecretKey(text []byte) (*SecretKey, error) {
+	raw, err := decodeKeyLine(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) != 158 || !bytes.Equal(raw[:2], algEd) || !bytes.Equal(raw[4:6], algChecksum) {
+		return nil, fmt.Errorf("invalid secret key")
+	}
+
+	if raw[2] != 0 || raw[3] != 0 {
+		return nil, fmt.Errorf("encrypted secret keys are not supported")
+	}
+
+	sk := &SecretKey{Key: ed25519.PrivateKey(raw[62:126])}
+	copy(sk.ID[:], raw[54:62])
+
+	if !bytes.Equal(sk.checksum(), raw[126:158]) {
+		return nil, fmt.Errorf("invalid secret key: checksum mismatch")
+	}
+
+	return sk, nil
+}
+
+// Sign returns the detached signature of data in minisign format.
+func (sk *SecretKey) Sign(data []byte, trustedComment string) []byte {
+	hash := blake2b.Sum512(data)
+	sig := ed25519.Sign(sk.Key, hash[:])
+
+	raw := make([]byte, 0, 74)
+	raw = append(raw, algEdHashed...)
+	raw = append(raw, sk.ID[:]...)
+	raw = append(raw, sig...)
+
+	trustedComment = strings.ReplaceAll(trustedComment, "\n", " ")
+	global := ed25519.Sign(sk.Key, append(sig, []byte(trustedComment)...))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "untrusted comment: signature from tbd secret key %s\n", sk.Public().KeyID())
+	fmt.Fprintf(&sb, "%s\n", base64.StdEncoding.EncodeToString(raw))
+	fmt.Fprintf(&sb, "trusted comment: %s\n", trustedComment)
+	fmt.Fprintf(&sb, "%s\n", base64.StdEncoding.EncodeToString(global))
+
+	return []byte(sb.String())
+}
+
+// Verifier checks detached signatures against a set of trusted keys.
+type Verifier struct {
+	Keys []*PublicKey
+}
+
+// Verify checks that sig is a valid signature of data, made
+// with one of the trusted keys, and returns the trusted comment.
+func (v *Verifier) Verify(data, sig []byte) (string, error) {
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) < 4 {
+		return "", fmt.Errorf("malformed signature")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 74 {
+		return "", fmt.Errorf("malformed signature")
+	}
+
+	var pk *PublicKey
+	for _, el := range v.Keys {
+		if bytes.Equal(el.ID[:], raw[2:10]) {
+			pk = el
+			break
+		}
+	}
+	if pk == nil {
+		return "", fmt.Errorf("signed with untrusted key %016X", binary.LittleEndian.Uint64(raw[2:10]))
+	}
+
+	msg := data
+	switch {
+	case bytes.Equal(raw[:2], algEdHashed):
+		hash := blake2b.Sum512(data)
+		msg = hash[:]
+	case !bytes.Equal(raw[:2], algEd):
+		return "", fmt.Errorf("unsupported signature algorithm")
+	}
+
+	sig = raw[10:]
+	if !ed25519.Verify(pk.Key, msg, sig) {
+		return "", fmt.Errorf("signature verification failed")
+	}
+
+	comment := strings.TrimRight(lines[2], "\r")
+	if !strings.HasPrefix(comment, "trusted comment: ") {
+		return "", fmt.Errorf("malformed signature: missing trusted comment")
+	}
+	comment = strings.TrimPrefix(comment, "trusted comment: ")
+
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || !ed25519.Verify(pk.Key, append(append([]byte{}, sig...), []byte(comment)...), global) {
+		return "", fmt.Errorf("trusted comment verification failed")
+	}
+
+	return comment, nil
+}
+
+// LoadPublicKeys reads all the '*.pub' public keys in dir.
+// A missing directory is not an error.
+func LoadPublicKeys(dir string) ([]*PublicKey, error) {
+	all, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*PublicKey, 0, len(all))
+	for _, el := range all {
+		pk, err := LoadPublicKey(el)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, pk)
+	}
+
+	return res, nil
+}
+
+// LoadPublicKey reads the public key stored in filename.
+func LoadPublicKey(filename string) (*PublicKey, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := ParsePublicKey(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return pk, nil
+}
+
+// DefaultConfigDir returns the directory where the tbd
+// configuration is stored (inside the user config dir).
+func DefaultConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tbd"), nil
+}
+
+// signatureURI returns the URI of the detached signature of uri.
+func signatureURI(uri string) string {
+	switch schemeOf(uri) {
+	case "http", "https", "file":
+		if u, err := url.Parse(uri); err == nil {
+			u.Path = u.Path + SignatureExt
+			u.RawPath = ""
+			return u.String()
+		}
+	}
+
+	return uri + SignatureExt
+}
+
+// decodeKeyLine decodes the base64 line of a minisign key,
+// skipping the optional comment line.
+func decodeKeyLine(text []byte) ([]byte, error) {
+	for _, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		return base64.StdEncoding.DecodeString(line)
+	}
+
+	return nil, fmt.Errorf("missing key")
+}
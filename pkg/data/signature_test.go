@@ -0,0 +1,138 @@
+package data
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeysRoundTrip(t *testing.T) {
+	pk, sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, _ := pk.MarshalText()
+	if !strings.HasPrefix(string(text), "untrusted comment: minisign public key "+pk.KeyID()) {
+		t.Errorf("unexpected public key comment: %s", text)
+	}
+
+	pk2, err := ParsePublicKey(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk2.ID != pk.ID || !pk2.Key.Equal(pk.Key) {
+		t.Errorf("public key mismatch")
+	}
+
+	text, _ = sk.MarshalText()
+	sk2, err := ParseSecretKey(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk2.ID != sk.ID || !sk2.Key.Equal(sk.Key) {
+		t.Errorf("secret key mismatch")
+	}
+
+	// corrupt the key material: the checksum must not match
+	raw, _ := decodeKeyLine(text)
+	raw[70] ^= 0xff
+	if _, err := ParseSecretKey([]byte(base64.StdEncoding.EncodeToString(raw))); err == nil {
+		t.Errorf("expected checksum error")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	pk, sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _ := GenerateKey()
+
+	data := []byte("{{ greeting }}")
+	sig := sk.Sign(data, "timestamp:1627309356\tfile:pod.tbd")
+
+	v := &Verifier{Keys: []*PublicKey{other, pk}}
+	comment, err := v.Verify(data, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment != "timestamp:1627309356\tfile:pod.tbd" {
+		t.Errorf("unexpected trusted comment [%s]", comment)
+	}
+
+	if _, err := v.Verify([]byte("{{ tampered }}"), sig); err == nil {
+		t.Errorf("expected error verifying tampered data")
+	}
+
+	tampered := strings.Replace(string(sig), "file:pod.tbd", "file:other.tbd", 1)
+	if _, err := v.Verify(data, []byte(tampered)); err == nil {
+		t.Errorf("expected error verifying tampered trusted comment")
+	}
+
+	untrusted := &Verifier{Keys: []*PublicKey{other}}
+	if _, err := untrusted.Verify(data, sig); err == nil || !strings.Contains(err.Error(), "untrusted key") {
+		t.Errorf("expected untrusted key error, got %v", err)
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	pk, sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// legacy minisign signatures sign the data instead of its hash
+	data := []byte("{{ greeting }}")
+	sig := ed25519.Sign(sk.Key, data)
+	raw := append(append([]byte("Ed"), sk.ID[:]...), sig...)
+	global := ed25519.Sign(sk.Key, append(append([]byte{}, sig...), []byte("legacy")...))
+
+	text := fmt.Sprintf("untrusted comment: legacy\n%s\ntrusted comment: legacy\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), base64.StdEncoding.EncodeToString(global))
+
+	v := &Verifier{Keys: []*PublicKey{pk}}
+	if _, err := v.Verify(data, []byte(text)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFetchSigned(t *testing.T) {
+	pk, sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := []byte("{{ greeting }}")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pod.tbd", "/unsigned.tbd":
+			w.Write(tpl)
+		case "/pod.tbd.sig":
+			w.Write(sk.Sign(tpl, "pod.tbd"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	f := &Fetcher{Verifier: &Verifier{Keys: []*PublicKey{pk}}}
+
+	data, err := f.Fetch(ts.URL+"/pod.tbd?ref=main", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(tpl) {
+		t.Errorf("got [%s] want [%s]", data, tpl)
+	}
+
+	_, err = f.Fetch(ts.URL+"/unsigned.tbd", 0)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+}
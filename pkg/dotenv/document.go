@@ -0,0 +1,311 @@
+package dotenv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Document is an env file kept in memory as it has been written:
+// key order, comments, blank lines and quoting style are preserved,
+// so that it can be edited and written back without reformatting.
+type Document struct {
+	lines    []*docLine
+	trailing bool
+}
+
+// docLine is a single line of a Document. Lines without
+// a key (comments and blank lines) are kept verbatim.
+type docLine struct {
+	raw string
+
+	lead   string // indentation and optional 'export '
+	key    string
+	sep    string // separator with the surrounding spaces
+	value  string // value as written, without quotes
+	quote  byte   // 0, '\'' or '"'
+	suffix string // trailing spaces and comments
+
+	dirty bool
+}
+
+// ParseDocument reads an env file from io.Reader keeping its layout.
+// Malformed lines are reported as *ParseError.
+func ParseDocument(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	if len(data) == 0 {
+		return doc, nil
+	}
+
+	doc.trailing = bytes.HasSuffix(data, []byte("\n"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for i := 1; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if isIgnoredLine(line) {
+			doc.lines = append(doc.lines, &docLine{raw: line})
+			continue
+		}
+
+		el, err := parseDocLine(line)
+		if err != nil {
+			return nil, &ParseError{
+				Line:   i,
+				Column: len(line) - len(strings.TrimLeft(line, " \t")) + 1,
+				Text:   line,
+				Err:    err,
+			}
+		}
+		doc.lines = append(doc.lines, el)
+	}
+
+	return doc, scanner.Err()
+}
+
+// Keys returns all the defined keys in order of appearance.
+func (d *Document) Keys() []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, el := range d.lines {
+		if el.key == "" || seen[el.key] {
+			continue
+		}
+		seen[el.key] = true
+		res = append(res, el.key)
+	}
+	return res
+}
+
+// Get returns the value of the specified key (quotes removed and
+// escapes resolved, but variables are not expanded).
+func (d *Document) Get(key string) (string, bool) {
+	el := d.find(key)
+	if el == nil {
+		return "", false
+	}
+
+	if el.quote == '"' {
+		return unescapeDoubleQuoted(el.value), true
+	}
+
+	return el.value, true
+}
+
+// Set changes the value of the specified key, keeping the
+// original quoting style where possible; new keys are appended
+// using the same separator style of the last entry.
+func (d *Document) Set(key, value string) {
+	el := d.find(key)
+	if el == nil {
+		el = &docLine{key: key, sep: "="}
+		for i := len(d.lines) - 1; i >= 0; i-- {
+			if d.lines[i].key != "" {
+				el.sep = d.lines[i].sep
+				break
+			}
+		}
+		if len(d.lines) == 0 {
+			d.trailing = true
+		}
+		d.lines = append(d.lines, el)
+	}
+
+	el.quote = quoteFor(value, el.quote)
+	switch el.quote {
+	case '"':
+		el.value = escapeDoubleQuoted(value)
+	default:
+		el.value = value
+	}
+	el.dirty = true
+}
+
+// Delete removes every definition of the specified key.
+// It reports whether the key was found.
+func (d *Document) Delete(key string) bool {
+	found := false
+	res := d.lines[:0]
+	for _, el := range d.lines {
+		if el.key == key {
+			found = true
+			continue
+		}
+		res = append(res, el)
+	}
+	d.lines = res
+	return found
+}
+
+// WriteTo serializes the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, d.String())
+	return int64(n), err
+}
+
+// Bytes returns the serialized document.
+func (d *Document) Bytes() []byte {
+	return []byte(d.String())
+}
+
+func (d *Document) String() string {
+	var sb strings.Builder
+	for i, el := range d.lines {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(el.String())
+	}
+
+	if len(d.lines) > 0 && d.trailing {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// find returns the last definition of the key,
+// the one that wins when the file is parsed.
+func (d *Document) find(key string) *docLine {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].key == key {
+			return d.lines[i]
+		}
+	}
+	return nil
+}
+
+func (l *docLine) String() string {
+	if l.key == "" || !l.dirty {
+		return l.raw
+	}
+
+	var sb strings.Builder
+	sb.WriteString(l.lead)
+	sb.WriteString(l.key)
+	sb.WriteString(l.sep)
+	if l.quote != 0 {
+		sb.WriteByte(l.quote)
+	}
+	sb.WriteString(l.value)
+	if l.quote != 0 {
+		sb.WriteByte(l.quote)
+	}
+	sb.WriteString(l.suffix)
+
+	return sb.String()
+}
+
+// parseDocLine splits a line in its parts using
+// the same separator rules of parseLine.
+func parseDocLine(line string) (*docLine, error) {
+	firstEquals := strings.Index(line, "=")
+	firstColon := strings.Index(line, ":")
+
+	idx := firstEquals
+	if firstColon != -1 && (firstColon < firstEquals || firstEquals == -1) {
+		idx = firstColon
+	}
+	if idx == -1 {
+		return nil, ErrMissingSeparator
+	}
+
+	el := &docLine{raw: line}
+
+	head := line[:idx]
+	name := strings.TrimRight(head, " \t")
+	el.key = exportRegex.ReplaceAllString(name, "$1")
+	el.lead = name[:len(name)-len(el.key)]
+
+	rest := line[idx+1:]
+	value := strings.TrimLeft(rest, " \t")
+	el.sep = head[len(name):] + line[idx:idx+1] + rest[:len(rest)-len(value)]
+
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') {
+		if end := closingQuote(value); end != -1 {
+			el.quote = value[0]
+			el.value = value[1:end]
+			el.suffix = value[end+1:]
+			return el, nil
+		}
+	}
+
+	// unquoted: everything up to a comment
+	end := len(value)
+	if i := strings.Index(value, " #"); i != -1 {
+		end = i
+	}
+	el.value = strings.TrimRight(value[:end], " \t\r")
+	el.suffix = value[len(el.value):]
+
+	return el, nil
+}
+
+// closingQuote returns the index of the quote closing
+// the one at the beginning of s, or -1 if not found.
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		if q == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == q {
+			return i
+		}
+	}
+	return -1
+}
+
+// quoteFor returns the quoting style needed by value,
+// preferring the current one if it is still valid.
+func quoteFor(value string, current byte) byte {
+	special := strings.ContainsAny(value, "\n\r\"'#") ||
+		strings.TrimSpace(value) != value
+
+	switch current {
+	case '"':
+		return current
+	case '\'':
+		if !strings.ContainsAny(value, "'\n\r") {
+			return current
+		}
+		return '"'
+	}
+
+	if special {
+		return '"'
+	}
+	return 0
+}
+
+func escapeDoubleQuoted(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return r.Replace(s)
+}
+
+func unescapeDoubleQuoted(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
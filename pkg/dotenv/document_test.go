@@ -0,0 +1,107 @@
+package dotenv
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleDocument = `# service settings
+export NAME = "web server"   # the name
+PORT: 8080
+
+GREETING='Hello, $NAME'
+URL=https://example.com/#anchor
+EMPTY=
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(sampleDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.String(); got != sampleDocument {
+		t.Errorf("got [%v] want [%v]", got, sampleDocument)
+	}
+
+	want := []string{"NAME", "PORT", "GREETING", "URL", "EMPTY"}
+	if got := strings.Join(doc.Keys(), ","); got != strings.Join(want, ",") {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestDocumentGet(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(sampleDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"NAME", "web server"},
+		{"PORT", "8080"},
+		{"GREETING", "Hello, $NAME"},
+		{"URL", "https://example.com/#anchor"},
+		{"EMPTY", ""},
+	}
+
+	for _, tc := range tests {
+		got, ok := doc.Get(tc.key)
+		if !ok {
+			t.Errorf("%s: not found", tc.key)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got [%v] want [%v]", tc.key, got, tc.want)
+		}
+	}
+
+	if _, ok := doc.Get("MISSING"); ok {
+		t.Errorf("MISSING: expected not found")
+	}
+}
+
+func TestDocumentEdit(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(sampleDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc.Set("NAME", `api "v2"`)
+	doc.Set("PORT", "9090")
+	doc.Set("GREETING", "it's me")
+	doc.Set("LEVEL", "debug mode")
+	doc.Delete("URL")
+
+	want := `# service settings
+export NAME = "api \"v2\""   # the name
+PORT: 9090
+
+GREETING="it's me"
+EMPTY=
+LEVEL=debug mode
+`
+	if got := doc.String(); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+
+	// the edited document must parse to the same values
+	env, err := Parse(strings.NewReader(doc.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range doc.Keys() {
+		v, _ := doc.Get(k)
+		if env[k] != v {
+			t.Errorf("%s: got [%v] want [%v]", k, env[k], v)
+		}
+	}
+}
+
+func TestDocumentParseError(t *testing.T) {
+	_, err := ParseDocument(strings.NewReader("A=1\nbroken\n"))
+	if err == nil || err.Error() != "line 2: missing '=' or ':' separator" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
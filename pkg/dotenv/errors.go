@@ -0,0 +1,36 @@
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMissingSeparator is reported when a line has neither
+// an '=' nor a ':' between the key and the value.
+var ErrMissingSeparator = errors.New("missing '=' or ':' separator")
+
+// ParseError describes a problem found on a specific line
+// of an env file.
+type ParseError struct {
+	// Source is the name of the env file (may be empty).
+	Source string
+	// Line is the 1-based line number.
+	Line int
+	// Column is the 1-based column where the offending text starts.
+	Column int
+	// Text is the offending line.
+	Text string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.Source, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,74 @@
+package dotenv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ExecFunc evaluates a command substitution returning its output.
+type ExecFunc func(command string) (string, error)
+
+// ErrExecRefused is returned (possibly wrapped) by an ExecFunc refusing
+// to run the command: the substitution is left as is and reported
+// to the Parser Warn func.
+var ErrExecRefused = errors.New("command substitution not allowed")
+
+// ShellExec returns an ExecFunc running commands with the system
+// shell; commands running longer than timeout (if greater than zero)
+// are killed. As in the shell, trailing newlines are removed from the
+// output. On failure the error includes the command stderr.
+func ShellExec(timeout time.Duration) ExecFunc {
+	return func(command string) (string, error) {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", command)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Start(); err != nil {
+			return "", fmt.Errorf("$(%s) failed: %v", command, err)
+		}
+
+		// do not wait for the output pipes to be closed by
+		// any orphaned child process once the command is killed
+		done := make(chan error, 1)
+		go func() {
+			done <- cmd.Wait()
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			return "", fmt.Errorf("$(%s) timed out after %v", command, timeout)
+		}
+
+		if err != nil {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				return "", fmt.Errorf("$(%s) failed: %v", command, err)
+			}
+			return "", fmt.Errorf("$(%s) failed: %v: %s", command, err, msg)
+		}
+
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+}
@@ -0,0 +1,146 @@
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParserExec(t *testing.T) {
+	var commands []string
+	p := &Parser{
+		Exec: func(command string) (string, error) {
+			commands = append(commands, command)
+			return "out(" + command + ")", nil
+		},
+	}
+
+	src := `HOST=$(hostname)
+NAME="build on $(uname -s) for ${HOST}"
+LITERAL='$(hostname)'
+ESCAPED=\$(hostname)
+ARG=$(echo ${NAME})
+`
+	env := map[string]string{}
+	if err := p.ParseInto(strings.NewReader(src), env); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"HOST":    "out(hostname)",
+		"NAME":    "build on out(uname -s) for out(hostname)",
+		"LITERAL": "$(hostname)",
+		"ESCAPED": "$(hostname)",
+		"ARG":     "out(echo build on out(uname -s) for out(hostname))",
+	}
+	for k, v := range want {
+		if got := env[k]; got != v {
+			t.Errorf("%s: got [%v] want [%v]", k, got, v)
+		}
+	}
+
+	if len(commands) != 3 {
+		t.Errorf("unexpected commands: %v", commands)
+	}
+}
+
+func TestParserWithoutExec(t *testing.T) {
+	p := &Parser{}
+
+	src := `HOST=$(hostname)
+NAME="build on $(uname -s)"
+`
+	env := map[string]string{}
+	if err := p.ParseInto(strings.NewReader(src), env); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"HOST": "$(hostname)",
+		"NAME": "build on $(uname -s)",
+	}
+	for k, v := range want {
+		if got := env[k]; got != v {
+			t.Errorf("%s: got [%v] want [%v]", k, got, v)
+		}
+	}
+}
+
+func TestParserExecRefused(t *testing.T) {
+	var warnings []error
+	p := &Parser{
+		Source: "prod.vars",
+		Exec: func(command string) (string, error) {
+			return "", fmt.Errorf("%w (use --allow-exec)", ErrExecRefused)
+		},
+		Warn: func(err error) {
+			warnings = append(warnings, err)
+		},
+	}
+
+	env := map[string]string{}
+	if err := p.ParseInto(strings.NewReader("A=1\nHOST=$(hostname)\n"), env); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := env["HOST"]; got != "$(hostname)" {
+		t.Errorf("got [%v] want [$(hostname)]", got)
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	want := "prod.vars:2: HOST: command substitution not allowed (use --allow-exec)"
+	if got := warnings[0].Error(); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestParserExecError(t *testing.T) {
+	errRefused := errors.New("refused")
+	p := &Parser{
+		Source: "prod.vars",
+		Exec: func(command string) (string, error) {
+			return "", errRefused
+		},
+	}
+
+	err := p.ParseInto(strings.NewReader("A=1\nHOST=$(hostname)\n"), map[string]string{})
+	if !errors.Is(err, errRefused) {
+		t.Fatalf("expected refused error, got %v", err)
+	}
+
+	if got, want := err.Error(), "prod.vars:2: refused"; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestShellExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	exec := ShellExec(5 * time.Second)
+
+	out, err := exec("echo hello; echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "hello" {
+		t.Errorf("got [%v] want [hello]", out)
+	}
+
+	_, err = exec("echo boom >&2; exit 3")
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected stderr in error, got %v", err)
+	}
+
+	exec = ShellExec(100 * time.Millisecond)
+	_, err = exec("sleep 5")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
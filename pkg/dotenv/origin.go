@@ -0,0 +1,57 @@
+package dotenv
+
+import (
+	"fmt"
+)
+
+// Builtin is the source name used for variables not coming from an env file.
+const Builtin = "builtin"
+
+// Origin tells where a variable has been defined.
+type Origin struct {
+	Source string `json:"source"`
+	Line   int    `json:"line,omitempty"`
+}
+
+func (o Origin) String() string {
+	if o.Line <= 0 {
+		return o.Source
+	}
+	return fmt.Sprintf("%s:%d", o.Source, o.Line)
+}
+
+// Provenance keeps track, for every key, of all the places
+// where it has been defined - in definition order.
+type Provenance map[string][]Origin
+
+// Add records a new definition of the specified key.
+func (p Provenance) Add(key string, o Origin) {
+	if p == nil {
+		return
+	}
+	p[key] = append(p[key], o)
+}
+
+// Origin returns where the current value of the key comes from.
+func (p Provenance) Origin(key string) (Origin, bool) {
+	all := p[key]
+	if len(all) == 0 {
+		return Origin{}, false
+	}
+	return all[len(all)-1], true
+}
+
+// Overridden returns the definitions of the key that have been
+// overridden by the current one, most recent first.
+func (p Provenance) Overridden(key string) []Origin {
+	all := p[key]
+	if len(all) <= 1 {
+		return nil
+	}
+
+	res := make([]Origin, 0, len(all)-1)
+	for i := len(all) - 2; i >= 0; i-- {
+		res = append(res, all[i])
+	}
+	return res
+}
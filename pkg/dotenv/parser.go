@@ -3,12 +3,30 @@ package dotenv
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
 	"regexp"
 	"strings"
 )
 
-func ParseInto(r io.Reader, envMap map[string]string) (err error) {
+// Parser reads env files keeping track
+// of where every key has been defined.
+type Parser struct {
+	// Source is the name of the env file, used in errors and origins.
+	Source string
+	// Provenance, if not nil, collects the origin of every parsed key.
+	Provenance Provenance
+	// Exec, if not nil, evaluates $(...) command substitutions
+	// in unquoted and double quoted values; otherwise they are left as is.
+	Exec ExecFunc
+	// Warn, if not nil, is called with the problems not stopping
+	// the parsing (i.e. substitutions refused with ErrExecRefused).
+	Warn func(err error)
+}
+
+// ParseInto reads an env file from io.Reader, storing keys and values into envMap.
+// Malformed lines are reported as *ParseError.
+func (p *Parser) ParseInto(r io.Reader, envMap map[string]string) (err error) {
 	var lines []string
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
@@ -19,20 +37,59 @@ func ParseInto(r io.Reader, envMap map[string]string) (err error) {
 		return
 	}
 
-	for _, fullLine := range lines {
+	// refused substitutions of the current line
+	var refused []error
+	exec := p.Exec
+	if exec != nil {
+		exec = func(command string) (string, error) {
+			out, err := p.Exec(command)
+			if errors.Is(err, ErrExecRefused) {
+				refused = append(refused, err)
+				return "$(" + command + ")", nil
+			}
+			return out, err
+		}
+	}
+
+	for i, fullLine := range lines {
 		if !isIgnoredLine(fullLine) {
 			var key, value string
-			key, value, err = parseLine(fullLine, envMap)
+			refused = nil
+			key, value, err = parseLine(fullLine, envMap, exec)
+
+			lineError := func(err error) *ParseError {
+				return &ParseError{
+					Source: p.Source,
+					Line:   i + 1,
+					Column: len(fullLine) - len(strings.TrimLeft(fullLine, " \t")) + 1,
+					Text:   fullLine,
+					Err:    err,
+				}
+			}
 
 			if err != nil {
+				err = lineError(err)
 				return
 			}
+			if p.Warn != nil {
+				for _, el := range refused {
+					p.Warn(lineError(fmt.Errorf("%s: %w", key, el)))
+				}
+			}
 			envMap[key] = value
+			p.Provenance.Add(key, Origin{Source: p.Source, Line: i + 1})
 		}
 	}
 	return
 }
 
+// ParseInto reads an env file from io.Reader, storing keys and values into envMap.
+// Malformed lines are reported as *ParseError.
+func ParseInto(r io.Reader, envMap map[string]string) error {
+	p := &Parser{}
+	return p.ParseInto(r, envMap)
+}
+
 // Parse reads an env file from io.Reader, returning a map of keys and values.
 func Parse(r io.Reader) (envMap map[string]string, err error) {
 	envMap = make(map[string]string)
@@ -42,7 +99,7 @@ func Parse(r io.Reader) (envMap map[string]string, err error) {
 
 var exportRegex = regexp.MustCompile(`^\s*(?:export\s+)?(.*?)\s*$`)
 
-func parseLine(line string, envMap map[string]string) (key string, value string, err error) {
+func parseLine(line string, envMap map[string]string, exec ExecFunc) (key string, value string, err error) {
 	if len(line) == 0 {
 		err = errors.New("zero length string")
 		return
@@ -80,7 +137,7 @@ func parseLine(line string, envMap map[string]string) (key string, value string,
 	}
 
 	if len(splitString) != 2 {
-		err = errors.New("Can't separate key from value")
+		err = ErrMissingSeparator
 		return
 	}
 
@@ -94,7 +151,7 @@ func parseLine(line string, envMap map[string]string) (key string, value string,
 	key = exportRegex.ReplaceAllString(splitString[0], "$1")
 
 	// Parse the value
-	value = parseValue(splitString[1], envMap)
+	value, err = parseValue(splitString[1], envMap, exec)
 	return
 }
 
@@ -105,7 +162,7 @@ var (
 	unescapeCharsRegex = regexp.MustCompile(`\\([^$])`)
 )
 
-func parseValue(value string, envMap map[string]string) string {
+func parseValue(value string, envMap map[string]string, exec ExecFunc) (string, error) {
 
 	// trim
 	value = strings.Trim(value, " ")
@@ -139,11 +196,43 @@ func parseValue(value string, envMap map[string]string) string {
 		}
 
 		if singleQuotes == nil {
-			value = expandVariables(value, envMap)
+			return expandValue(value, envMap, exec)
 		}
 	}
 
-	return value
+	return value, nil
+}
+
+var commandRegex = regexp.MustCompile(`(\\)?\$\(([^()]*)\)`)
+
+// expandValue evaluates the command substitutions (if exec is not nil)
+// and expands the variables; commands output is not further expanded.
+func expandValue(v string, m map[string]string, exec ExecFunc) (string, error) {
+	if exec == nil {
+		return expandVariables(v, m), nil
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range commandRegex.FindAllStringSubmatchIndex(v, -1) {
+		if loc[2] != -1 {
+			// escaped
+			continue
+		}
+
+		sb.WriteString(expandVariables(v[last:loc[0]], m))
+
+		out, err := exec(expandVariables(v[loc[4]:loc[5]], m))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(out)
+
+		last = loc[1]
+	}
+	sb.WriteString(expandVariables(v[last:], m))
+
+	return sb.String(), nil
 }
 
 var expandVarRegex = regexp.MustCompile(`(\\)?(\$)(\()?\{?([A-Z0-9_]+)?\}?`)
@@ -0,0 +1,91 @@
+package dotenv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# comment
+GREETING: Greetings
+export NAME = "Pinco Pallo"
+quoted = 'no $GREETING'
+expanded = "${GREETING} ${NAME}"
+`
+	env, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"GREETING": "Greetings",
+		"NAME":     "Pinco Pallo",
+		"quoted":   "no $GREETING",
+		"expanded": "Greetings Pinco Pallo",
+	}
+	for k, v := range want {
+		if got := env[k]; got != v {
+			t.Errorf("%s: got [%v] want [%v]", k, got, v)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	src := "A=1\n\n  this line is broken\nB=2\n"
+
+	_, err := Parse(strings.NewReader(src))
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+
+	if perr.Line != 3 {
+		t.Errorf("line: got [%d] want [3]", perr.Line)
+	}
+	if perr.Column != 3 {
+		t.Errorf("column: got [%d] want [3]", perr.Column)
+	}
+	if !errors.Is(err, ErrMissingSeparator) {
+		t.Errorf("expected ErrMissingSeparator, got %v", perr.Err)
+	}
+
+	perr.Source = "prod.vars"
+	want := "prod.vars:3: missing '=' or ':' separator"
+	if got := err.Error(); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestParserProvenance(t *testing.T) {
+	env := map[string]string{"OS": "linux"}
+	prov := Provenance{}
+	prov.Add("OS", Origin{Source: Builtin})
+
+	p := &Parser{Source: "base.vars", Provenance: prov}
+	if err := p.ParseInto(strings.NewReader("NAME=a\nOS=plan9\n"), env); err != nil {
+		t.Fatal(err)
+	}
+
+	p = &Parser{Source: "prod.vars", Provenance: prov}
+	if err := p.ParseInto(strings.NewReader("# prod\nOS=windows\n"), env); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := prov.Origin("OS"); got.String() != "prod.vars:2" {
+		t.Errorf("got [%v] want [prod.vars:2]", got)
+	}
+
+	prev := prov.Overridden("OS")
+	if len(prev) != 2 || prev[0].String() != "base.vars:2" || prev[1].String() != Builtin {
+		t.Errorf("unexpected overridden chain %v", prev)
+	}
+
+	if got, _ := prov.Origin("NAME"); got.String() != "base.vars:1" {
+		t.Errorf("got [%v] want [base.vars:1]", got)
+	}
+	if prev := prov.Overridden("NAME"); len(prev) != 0 {
+		t.Errorf("unexpected overridden chain %v", prev)
+	}
+}
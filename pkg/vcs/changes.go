@@ -0,0 +1,167 @@
+package vcs
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Component is a part of a monorepo identified by its paths.
+type Component struct {
+	// Name is the component name (i.e. 'api').
+	Name string
+	// Paths are the directories, files or globs of the component
+	// (i.e. 'services/api' or 'libs/*/proto'), relative to the root.
+	Paths []string
+}
+
+// ParseComponent parses a 'NAME=PATH' mapping.
+func ParseComponent(s string) (Component, error) {
+	idx := strings.Index(s, "=")
+	if idx <= 0 || idx == len(s)-1 {
+		return Component{}, fmt.Errorf("invalid component '%s', expected NAME=PATH", s)
+	}
+
+	name, p := strings.TrimSpace(s[:idx]), strings.TrimSpace(s[idx+1:])
+	if name == "" {
+		return Component{}, fmt.Errorf("invalid component '%s': missing name", s)
+	}
+	if _, err := path.Match(p, ""); err != nil {
+		return Component{}, fmt.Errorf("invalid path '%s' of component '%s': %v", p, name, err)
+	}
+	if trimmed := strings.TrimSuffix(strings.TrimPrefix(p, "./"), "/"); trimmed == "" || trimmed == "." {
+		return Component{}, fmt.Errorf("invalid path '%s' of component '%s': the whole repository is not a component", p, name)
+	}
+
+	switch v := ComponentVar(name); v {
+	case RepoChangedBase, RepoChangedPaths, RepoChangedComponents:
+		return Component{}, fmt.Errorf("invalid component '%s': its variable would be %s", name, v)
+	}
+
+	return Component{Name: name, Paths: []string{p}}, nil
+}
+
+// ParseComponents parses the 'NAME=PATH' mappings, merging the paths
+// of the components with the same name; distinct names with the same
+// variable (i.e. 'my-api' and 'my_api') are reported as an error.
+func ParseComponents(mappings []string) ([]Component, error) {
+	res := []Component{}
+	names := map[string]string{}
+	for _, el := range mappings {
+		c, err := ParseComponent(el)
+		if err != nil {
+			return nil, err
+		}
+
+		v := ComponentVar(c.Name)
+		if other, ok := names[v]; ok && other != c.Name {
+			return nil, fmt.Errorf("components '%s' and '%s' would both be flagged by %s", other, c.Name, v)
+		}
+		names[v] = c.Name
+
+		found := false
+		for i := range res {
+			if res[i].Name == c.Name {
+				res[i].Paths = append(res[i].Paths, c.Paths...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, c)
+		}
+	}
+
+	return res, nil
+}
+
+// Match reports whether the file (a slash separated path relative
+// to the root) or one of its parent directories matches a component path.
+func (c Component) Match(file string) bool {
+	for _, el := range c.Paths {
+		pattern := strings.TrimSuffix(strings.TrimPrefix(el, "./"), "/")
+		for p := file; p != "." && p != "/"; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ComponentVar returns the variable flagging the
+// changes of a component (i.e. 'REPO_CHANGED_API').
+func ComponentVar(name string) string {
+	res := []rune(strings.ToUpper(name))
+	for i, r := range res {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			res[i] = '_'
+		}
+	}
+	return "REPO_CHANGED_" + string(res)
+}
+
+// ChangedComponents returns the names of the components
+// (in the specified order) with at least a changed file.
+func ChangedComponents(components []Component, files []string) []string {
+	res := []string{}
+	for _, c := range components {
+		for _, el := range files {
+			if c.Match(el) {
+				res = append(res, c.Name)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// ChangedPaths returns the (sorted) paths of the files changed in head
+// since its merge base with base, like 'git diff base...head'
+// (all the files of head if base is nil).
+func ChangedPaths(head, base *object.Commit) ([]string, error) {
+	to, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var from *object.Tree
+	if base != nil {
+		bases, err := head.MergeBase(base)
+		if err != nil {
+			return nil, err
+		}
+		if len(bases) > 0 {
+			base = bases[0]
+		} // else unrelated histories: compare the trees
+		if from, err = base.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, el := range changes {
+		if el.From.Name != "" {
+			set[el.From.Name] = true
+		}
+		if el.To.Name != "" {
+			set[el.To.Name] = true
+		}
+	}
+
+	res := make([]string, 0, len(set))
+	for k := range set {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
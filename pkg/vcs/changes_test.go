@@ -0,0 +1,120 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComponents(t *testing.T) {
+	got, err := ParseComponents([]string{"api=services/api", "web=web/", "api=libs/*/proto"})
+	require.NoError(t, err)
+	assert.Equal(t, []Component{
+		{Name: "api", Paths: []string{"services/api", "libs/*/proto"}},
+		{Name: "web", Paths: []string{"web/"}},
+	}, got)
+
+	for _, el := range []string{"api", "=services/api", " =x", "api=", "api=[", "api=./", "api=/", "paths=docs"} {
+		_, err := ParseComponents([]string{el})
+		assert.Error(t, err, el)
+	}
+
+	_, err = ParseComponents([]string{"my-api=services/api", "my_api=services/legacy"})
+	assert.EqualError(t, err, "components 'my-api' and 'my_api' would both be flagged by REPO_CHANGED_MY_API")
+}
+
+func TestComponentMatch(t *testing.T) {
+	c := Component{Name: "api", Paths: []string{"./services/api/", "libs/*/proto", "go.mod"}}
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"services/api/main.go", true},
+		{"services/api/v1/handler.go", true},
+		{"services/apigw/main.go", false},
+		{"libs/auth/proto/auth.proto", true},
+		{"libs/auth/client.go", false},
+		{"go.mod", true},
+		{"go.sum", false},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, c.Match(tc.file), tc.file)
+	}
+}
+
+func TestComponentVar(t *testing.T) {
+	assert.Equal(t, "REPO_CHANGED_API", ComponentVar("api"))
+	assert.Equal(t, "REPO_CHANGED_BILLING_WORKER_2", ComponentVar("billing-worker.2"))
+}
+
+func TestChangesMetadata(t *testing.T) {
+	clearCIEnv(t)
+
+	r := newTestRepo(t)
+	r.remote("origin", "git@github.com:lucasepe/monorepo.git")
+	r.commit("services/api/main.go", "package main", "feat: api")
+	first := r.commit("web/index.html", "<html>", "feat: web")
+	r.tag("v1.0.0", first, "")
+
+	r.checkout("feature")
+	r.commit("services/api/main.go", "package main // v2", "fix: api")
+	r.commit("docs/README.md", "# docs", "docs: readme")
+
+	r.checkout("master")
+	r.commit("web/index.html", "<html></html>", "fix: web")
+	r.checkout("feature")
+
+	components, err := ParseComponents([]string{"api=services/api", "web=web", "docs=docs/*.md"})
+	require.NoError(t, err)
+
+	// since the latest tag
+	meta := map[string]string{}
+	warnings, err := CollectGitRepoMetadata(r.dir, Options{Components: components}, meta)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "v1.0.0", meta[RepoChangedBase])
+	assert.Equal(t, "docs/README.md,services/api/main.go", meta[RepoChangedPaths])
+	assert.Equal(t, "api,docs", meta[RepoChangedComponents])
+	assert.Equal(t, "true", meta["REPO_CHANGED_API"])
+	assert.Equal(t, "false", meta["REPO_CHANGED_WEB"])
+	assert.Equal(t, "true", meta["REPO_CHANGED_DOCS"])
+
+	// since the merge base with a branch (master changes are ignored)
+	meta = map[string]string{}
+	warnings, err = CollectGitRepoMetadata(r.dir, Options{Base: "master", Components: components}, meta)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "master", meta[RepoChangedBase])
+	assert.Equal(t, "docs/README.md,services/api/main.go", meta[RepoChangedPaths])
+	assert.Equal(t, "false", meta["REPO_CHANGED_WEB"])
+
+	// unknown base
+	meta = map[string]string{}
+	warnings, err = CollectGitRepoMetadata(r.dir, Options{Base: "missing", Components: components}, meta)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "changes", warnings[0].Topic)
+	assert.Contains(t, warnings[0].Vars, "REPO_CHANGED_API")
+	assert.NotContains(t, meta, RepoChangedPaths)
+}
+
+func TestChangedPathsWithoutBase(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("a.txt", "a", "first")
+	hash := r.commit("b/c.txt", "c", "second")
+
+	head, err := r.repo.CommitObject(hash)
+	require.NoError(t, err)
+
+	files, err := ChangedPaths(head, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b/c.txt"}, files)
+
+	// HEAD itself
+	files, err = ChangedPaths(head, head)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,218 @@
+package vcs
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// branchFromEnv returns the branch being built as reported by
+// the CI environment variables (useful when HEAD is detached).
+func branchFromEnv(getenv func(string) string) string {
+	// GitHub Actions
+	if v := getenv("GITHUB_HEAD_REF"); v != "" {
+		return v
+	}
+	if getenv("GITHUB_REF_TYPE") == "branch" {
+		if v := getenv("GITHUB_REF_NAME"); v != "" {
+			return v
+		}
+	}
+
+	// GitLab CI
+	if v := getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"); v != "" {
+		return v
+	}
+	if v := getenv("CI_COMMIT_BRANCH"); v != "" {
+		return v
+	}
+	if getenv("CI_COMMIT_TAG") == "" {
+		if v := getenv("CI_COMMIT_REF_NAME"); v != "" {
+			return v
+		}
+	}
+
+	// Azure Pipelines
+	if v := getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH"); v != "" {
+		return strings.TrimPrefix(v, "refs/heads/")
+	}
+	if v := getenv("BUILD_SOURCEBRANCH"); strings.HasPrefix(v, "refs/heads/") {
+		return strings.TrimPrefix(v, "refs/heads/")
+	}
+
+	// Bitbucket, Buildkite, CircleCI, Drone, Travis CI and Jenkins
+	for _, name := range []string{
+		"BITBUCKET_BRANCH",
+		"BUILDKITE_BRANCH",
+		"CIRCLE_BRANCH",
+		"DRONE_SOURCE_BRANCH",
+		"TRAVIS_PULL_REQUEST_BRANCH",
+		"TRAVIS_BRANCH",
+		"CHANGE_BRANCH",
+		"BRANCH_NAME",
+	} {
+		if v := getenv(name); v != "" {
+			return v
+		}
+	}
+
+	// Jenkins git plugin (i.e. 'origin/main')
+	if v := getenv("GIT_BRANCH"); v != "" {
+		if idx := strings.Index(v, "/"); idx != -1 && strings.HasPrefix(v, "origin/") {
+			return v[idx+1:]
+		}
+		return v
+	}
+
+	return ""
+}
+
+// firstEnv returns the first non empty variable.
+func firstEnv(getenv func(string) string, names ...string) string {
+	for _, el := range names {
+		if v := getenv(el); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// commitFromEnv returns the commit being built.
+func commitFromEnv(getenv func(string) string) string {
+	return firstEnv(getenv,
+		"GITHUB_SHA",
+		"CI_COMMIT_SHA",
+		"BUILD_SOURCEVERSION",
+		"BITBUCKET_COMMIT",
+		"BUILDKITE_COMMIT",
+		"CIRCLE_SHA1",
+		"DRONE_COMMIT_SHA",
+		"TRAVIS_COMMIT",
+		"GIT_COMMIT",
+	)
+}
+
+// tagFromEnv returns the tag being built (if any).
+func tagFromEnv(getenv func(string) string) string {
+	if getenv("GITHUB_REF_TYPE") == "tag" {
+		return getenv("GITHUB_REF_NAME")
+	}
+	if v := getenv("BUILD_SOURCEBRANCH"); strings.HasPrefix(v, "refs/tags/") {
+		return strings.TrimPrefix(v, "refs/tags/")
+	}
+
+	return firstEnv(getenv,
+		"CI_COMMIT_TAG",
+		"BITBUCKET_TAG",
+		"BUILDKITE_TAG",
+		"CIRCLE_TAG",
+		"DRONE_TAG",
+		"TRAVIS_TAG",
+		"TAG_NAME",
+	)
+}
+
+// urlFromEnv returns the remote URL of the repository being built.
+func urlFromEnv(getenv func(string) string) string {
+	if repo := getenv("GITHUB_REPOSITORY"); repo != "" {
+		server := getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		return strings.TrimSuffix(server, "/") + "/" + repo
+	}
+
+	return firstEnv(getenv,
+		"CI_PROJECT_URL",
+		"BUILD_REPOSITORY_URI",
+		"BITBUCKET_GIT_HTTP_ORIGIN",
+		"BUILDKITE_REPO",
+		"CIRCLE_REPOSITORY_URL",
+		"DRONE_GIT_HTTP_URL",
+		"GIT_URL",
+	)
+}
+
+// CIProvider derives the metadata from the environment
+// variables set by the common CI services.
+type CIProvider struct {
+	// Getenv looks up the variables (os.Getenv if nil).
+	Getenv func(string) string
+}
+
+// Name returns 'ci'.
+func (p *CIProvider) Name() string {
+	return "ci"
+}
+
+// Metadata sets the commit, branch, tag and URL variables;
+// path and options are ignored.
+func (p *CIProvider) Metadata(path string, opts Options, meta map[string]string) ([]Warning, error) {
+	getenv := p.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	commit := commitFromEnv(getenv)
+	if commit == "" {
+		return nil, notDetected(p.Name(), errors.New("no CI variable names the commit"))
+	}
+
+	var warnings []Warning
+	warn := func(topic string, err error, vars ...string) {
+		warnings = append(warnings, Warning{Topic: topic, Vars: vars, Err: err})
+	}
+
+	meta[RepoCommit] = commit
+	meta[RepoCommitShort] = commit
+	if len(commit) > shortHashLength {
+		meta[RepoCommitShort] = commit[:shortHashLength]
+	}
+
+	if branch := branchFromEnv(getenv); branch != "" {
+		meta[RepoBranch] = branch
+		meta[RepoBranchSlug] = Slug(branch)
+	}
+
+	// same as the git provider: the last segment, without 'v'
+	tag := tagFromEnv(getenv)
+	if tag != "" {
+		short := tag[strings.LastIndex(tag, "/")+1:]
+		meta[RepoTag] = short
+		if strings.HasPrefix(short, "v") {
+			meta[RepoTagClean] = short[1:]
+		}
+	}
+
+	meta[RepoURL] = ""
+	meta[RepoName] = ""
+
+	raw := urlFromEnv(getenv)
+	if raw == "" {
+		warn("remote", errors.New("no CI variable names the repository URL"), RepoURL, RepoName)
+		return warnings, nil
+	}
+
+	info, err := ParseRemoteURL(raw)
+	if err != nil {
+		warn("remote", err, RepoURL, RepoName)
+		return warnings, nil
+	}
+
+	meta[RepoURL] = info.URL
+	meta[RepoName] = info.Name
+	if info.Host != "" {
+		meta[RepoHost] = info.Host
+		meta[RepoRoot] = info.Root()
+		meta[RepoNamespace] = info.Namespace
+	}
+	if info.Forge != "" {
+		meta[RepoForge] = info.Forge
+		meta[RepoCommitURL] = info.CommitURL(commit)
+		if tag != "" {
+			meta[RepoTagURL] = info.TagURL(tag)
+		}
+	}
+
+	return warnings, nil
+}
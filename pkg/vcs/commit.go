@@ -0,0 +1,34 @@
+package vcs
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// HeadCommitFromGitRepo returns the commit HEAD points to.
+func HeadCommitFromGitRepo(repository *git.Repository) (*object.Commit, error) {
+	headRef, err := repository.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.CommitObject(headRef.Hash())
+}
+
+// CommitSubject returns the subject of the commit message: like
+// 'git log --format=%s', the lines of the first paragraph joined by spaces.
+func CommitSubject(commit *object.Commit) string {
+	msg := strings.TrimSpace(strings.ReplaceAll(commit.Message, "\r\n", "\n"))
+	if idx := strings.Index(msg, "\n\n"); idx != -1 {
+		msg = msg[:idx]
+	}
+
+	lines := strings.Split(msg, "\n")
+	for i, el := range lines {
+		lines[i] = strings.TrimSpace(el)
+	}
+
+	return strings.Join(lines, " ")
+}
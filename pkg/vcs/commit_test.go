@@ -0,0 +1,38 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitSubject(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"Fix typo\n", "Fix typo"},
+		{"Fix typo\n\nin the README", "Fix typo"},
+		{"Add feature\nspanning two lines\n\nbody", "Add feature spanning two lines"},
+		{"\n\n  Leading blank lines  \r\n\r\nbody\r\n", "Leading blank lines"},
+		{"", ""},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, CommitSubject(&object.Commit{Message: tc.msg}), tc.msg)
+	}
+}
+
+func TestCommitMetadata(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("README.md", "hello", "Add README\n\nWith a warm welcome.\n")
+
+	meta := r.metadata()
+	assert.Equal(t, "Pinco Pallo", meta[RepoCommitAuthor])
+	assert.Equal(t, "pinco.pallo@example.com", meta[RepoCommitEmail])
+	assert.Equal(t, "2021-07-26T14:01:00Z", meta[RepoCommitDate])
+	assert.Equal(t, "1627308060", meta[RepoCommitTimestamp])
+	assert.Equal(t, "Add README", meta[RepoCommitSubject])
+	assert.Equal(t, "Add README\n\nWith a warm welcome.", meta[RepoCommitMessage])
+}
@@ -0,0 +1,89 @@
+package vcs
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Bump is the part of a semantic version to increment.
+type Bump int
+
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	}
+	return "none"
+}
+
+// conventionalRegex matches the header of a Conventional Commit
+// message (https://www.conventionalcommits.org): 'type(scope)!: '.
+var conventionalRegex = regexp.MustCompile(`^([a-zA-Z]+)(\([^()\r\n]*\))?(!)?: `)
+
+// CommitBump returns the bump level implied by a Conventional Commit
+// message: major for breaking changes ('!' after the type or a
+// 'BREAKING CHANGE:' footer), minor for 'feat', patch for 'fix'
+// and none for anything else.
+func CommitBump(msg string) Bump {
+	m := conventionalRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return BumpNone
+	}
+
+	if m[3] == "!" {
+		return BumpMajor
+	}
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			return BumpMajor
+		}
+	}
+
+	switch strings.ToLower(m[1]) {
+	case "feat":
+		return BumpMinor
+	case "fix":
+		return BumpPatch
+	}
+
+	return BumpNone
+}
+
+// ConventionalBump returns the highest bump level implied by the
+// commits made since the specified one (since the root commit if nil):
+// at least a patch if there is any commit, none otherwise.
+func ConventionalBump(head, since *object.Commit) (Bump, error) {
+	seen := map[plumbing.Hash]bool{}
+	if since != nil {
+		if err := ancestorsOf(since, seen); err != nil {
+			return BumpNone, err
+		}
+	}
+
+	res := BumpNone
+	err := object.NewCommitPreorderIter(head, seen, nil).ForEach(func(c *object.Commit) error {
+		if b := CommitBump(c.Message); b > res {
+			res = b
+		}
+		if res < BumpPatch {
+			res = BumpPatch
+		}
+		return nil
+	})
+
+	return res, err
+}
@@ -0,0 +1,77 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitBump(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want Bump
+	}{
+		{"fix: off by one", BumpPatch},
+		{"fix(parser): off by one", BumpPatch},
+		{"feat: add --json", BumpMinor},
+		{"Feat(cli): add --json", BumpMinor},
+		{"feat!: drop the legacy syntax", BumpMajor},
+		{"refactor(api)!: rename options", BumpMajor},
+		{"fix: new defaults\n\nBREAKING CHANGE: timeouts are shorter", BumpMajor},
+		{"docs: typo", BumpNone},
+		{"Update README", BumpNone},
+		{"feat:missing space", BumpNone},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, CommitBump(tc.msg), tc.msg)
+	}
+}
+
+func TestNextVersionMetadata(t *testing.T) {
+	r := newTestRepo(t)
+
+	opts := Options{ConventionalCommits: true}
+	metadata := func() map[string]string {
+		meta := map[string]string{}
+		require.NoError(t, GitRepoMetadataWithOptions(r.dir, opts, meta))
+		return meta
+	}
+
+	// no tags: starting from 0.0.0
+	first := r.commit("README.md", "one", "docs: first")
+	meta := metadata()
+	assert.Equal(t, "0.0.1", meta[RepoNextPatch])
+	assert.Equal(t, "0.1.0", meta[RepoNextMinor])
+	assert.Equal(t, "1.0.0", meta[RepoNextMajor])
+	assert.Equal(t, "0.0.1", meta[RepoNextVersion])
+	assert.NotContains(t, meta, RepoTagMajor)
+
+	r.tag("v1.4.2-rc.1+build.5", first, "")
+	meta = metadata()
+	assert.Equal(t, "1", meta[RepoTagMajor])
+	assert.Equal(t, "4", meta[RepoTagMinor])
+	assert.Equal(t, "2", meta[RepoTagPatch])
+	assert.Equal(t, "rc.1", meta[RepoTagPrerelease])
+	assert.Equal(t, "build.5", meta[RepoTagBuild])
+	assert.Equal(t, "1.4.2", meta[RepoNextPatch])
+	assert.Equal(t, "1.5.0", meta[RepoNextMinor])
+	assert.Equal(t, "2.0.0", meta[RepoNextMajor])
+	// no commits since the tag
+	assert.Equal(t, "1.4.2-rc.1", meta[RepoNextVersion])
+
+	second := r.commit("README.md", "two", "fix: second")
+	r.tag("v1.4.2", second, "")
+	r.commit("README.md", "three", "chore: third")
+	assert.Equal(t, "1.4.3", metadata()[RepoNextVersion])
+
+	r.commit("README.md", "four", "feat: fourth")
+	assert.Equal(t, "1.5.0", metadata()[RepoNextVersion])
+
+	r.commit("README.md", "five", "fix: fifth\n\nBREAKING CHANGE: everything")
+	assert.Equal(t, "2.0.0", metadata()[RepoNextVersion])
+
+	opts.ConventionalCommits = false
+	assert.NotContains(t, metadata(), RepoNextVersion)
+}
@@ -0,0 +1,142 @@
+package vcs
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// shortHashLength is the length of abbreviated commit hashes.
+const shortHashLength = 7
+
+// Description identifies HEAD relative to the nearest reachable tag.
+type Description struct {
+	// Tag is the short name of the nearest tag (empty if none is reachable).
+	Tag string
+	// Distance is the number of commits since Tag
+	// (or since the root commit if there is no tag).
+	Distance int
+	// Hash is the HEAD commit.
+	Hash plumbing.Hash
+	// Dirty is true if tracked files have uncommitted changes.
+	Dirty bool
+}
+
+// Short returns the abbreviated hash of the HEAD commit.
+func (d *Description) Short() string {
+	return d.Hash.String()[:shortHashLength]
+}
+
+// String formats the description like 'git describe --tags --dirty'
+// (i.e. 'v1.2.0-3-g1a2b3c4-dirty'), using the abbreviated hash
+// alone when there is no tag.
+func (d *Description) String() string {
+	var res string
+	switch {
+	case d.Tag == "":
+		res = d.Short()
+	case d.Distance == 0:
+		res = d.Tag
+	default:
+		res = fmt.Sprintf("%s-%d-g%s", d.Tag, d.Distance, d.Short())
+	}
+
+	if d.Dirty {
+		res += "-dirty"
+	}
+
+	return res
+}
+
+// DescribeGitRepo finds the tag nearest to HEAD among its ancestors
+// and counts the commits made since then.
+func DescribeGitRepo(repository *git.Repository) (*Description, error) {
+	tags, err := ReachableTags(repository, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := describe(repository, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := DirtyFiles(repository, true)
+	if err != nil {
+		return nil, err
+	}
+	res.Dirty = len(files) > 0
+
+	return res, nil
+}
+
+// describe is DescribeGitRepo, considering only the
+// specified tags and without the worktree status.
+func describe(repository *git.Repository, tags []Tag) (*Description, error) {
+	headRef, err := repository.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repository.CommitObject(headRef.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	byCommit := map[plumbing.Hash][]Tag{}
+	for _, el := range tags {
+		byCommit[el.Commit.Hash] = append(byCommit[el.Commit.Hash], el)
+	}
+
+	res := &Description{Hash: head.Hash}
+
+	// breadth first, so that the nearest tag is found first
+	var tagged *object.Commit
+	err = object.NewCommitIterBSF(head, nil, nil).ForEach(func(c *object.Commit) error {
+		if all, ok := byCommit[c.Hash]; ok {
+			res.Tag = LatestTag(all).Name
+			tagged = c
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Distance, err = commitsSince(head, tagged); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// commitsSince counts the commits reachable from head but
+// not from since (all the commits reachable if since is nil).
+func commitsSince(head, since *object.Commit) (int, error) {
+	seen := map[plumbing.Hash]bool{}
+	if since != nil {
+		if err := ancestorsOf(since, seen); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	err := object.NewCommitPreorderIter(head, seen, nil).ForEach(func(c *object.Commit) error {
+		count++
+		return nil
+	})
+
+	return count, err
+}
+
+// ancestorsOf adds to seen the commit and all its ancestors.
+func ancestorsOf(commit *object.Commit, seen map[plumbing.Hash]bool) error {
+	return object.NewCommitPreorderIter(commit, nil, nil).ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+}
@@ -0,0 +1,99 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tag creates a lightweight (or annotated if msg is not empty) tag on hash.
+func (r *testRepo) tag(name string, hash plumbing.Hash, msg string) {
+	var opts *git.CreateTagOptions
+	if msg != "" {
+		opts = &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "Pinco Pallo", Email: "pinco.pallo@example.com", When: r.when},
+			Message: msg,
+		}
+	}
+
+	_, err := r.repo.CreateTag(name, hash, opts)
+	require.NoError(r.t, err)
+}
+
+// describe returns the description of the repository HEAD.
+func (r *testRepo) describe() *Description {
+	repo, err := OpenGitRepo(r.dir)
+	require.NoError(r.t, err)
+
+	desc, err := DescribeGitRepo(repo)
+	require.NoError(r.t, err)
+	return desc
+}
+
+func TestDescribeGitRepo(t *testing.T) {
+	r := newTestRepo(t)
+
+	first := r.commit("README.md", "one", "first")
+	desc := r.describe()
+	assert.Equal(t, first.String()[:7], desc.String())
+	assert.Equal(t, 1, desc.Distance)
+
+	r.tag("v0.1.0", first, "")
+	desc = r.describe()
+	assert.Equal(t, "v0.1.0", desc.String())
+	assert.Equal(t, 0, desc.Distance)
+
+	r.commit("README.md", "two", "second")
+	third := r.commit("README.md", "three", "third")
+	desc = r.describe()
+	assert.Equal(t, "v0.1.0-2-g"+third.String()[:7], desc.String())
+	assert.Equal(t, 2, desc.Distance)
+
+	// annotated tags are resolved to their commit
+	r.tag("v0.2.0", third, "second release")
+	assert.Equal(t, "v0.2.0", r.describe().String())
+
+	// untracked files are ignored, modified ones are not
+	r.write("notes.txt", "untracked")
+	assert.Equal(t, "v0.2.0", r.describe().String())
+	r.write("README.md", "changed")
+	assert.Equal(t, "v0.2.0-dirty", r.describe().String())
+}
+
+func TestDescribeGitRepoNearestTag(t *testing.T) {
+	r := newTestRepo(t)
+
+	base := r.commit("README.md", "base", "base")
+	r.tag("v1.0.0", base, "")
+
+	// a newer tag on a branch not reachable from HEAD
+	r.checkout("other")
+	other := r.commit("other.txt", "other", "other")
+	r.tag("v9.0.0", other, "")
+
+	r.checkout("master")
+	head := r.commit("README.md", "main", "main")
+
+	desc := r.describe()
+	assert.Equal(t, "v1.0.0", desc.Tag)
+	assert.Equal(t, 1, desc.Distance)
+	assert.Equal(t, head, desc.Hash)
+	assert.Equal(t, head.String()[:7], desc.Short())
+}
+
+func TestVersionMetadata(t *testing.T) {
+	r := newTestRepo(t)
+
+	first := r.commit("README.md", "one", "first")
+	r.tag("v1.0.0", first, "")
+	head := r.commit("README.md", "two", "second")
+
+	meta := r.metadata()
+	assert.Equal(t, head.String()[:7], meta[RepoCommitShort])
+	assert.Equal(t, "1", meta[RepoCommitsSinceTag])
+	assert.Equal(t, "v1.0.0-1-g"+head.String()[:7], meta[RepoVersion])
+}
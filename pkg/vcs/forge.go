@@ -0,0 +1,207 @@
+package vcs
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	gitUrls "github.com/whilp/git-urls"
+)
+
+// Forges recognized by ParseRemoteURL.
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	Bitbucket = "bitbucket"
+	Gitea     = "gitea"
+	Azure     = "azure"
+)
+
+// RemoteURL is a remote repository URL
+// parsed according to its forge.
+type RemoteURL struct {
+	// Forge is the hosting service (empty if unknown).
+	Forge string
+	// Host is the web host (i.e. 'gitlab.com').
+	Host string
+	// Namespace is the full path of the repository owner
+	// (i.e. 'group/subgroup' or 'organization/project').
+	Namespace string
+	// Name is the repository name.
+	Name string
+	// URL is the web URL of the repository (without credentials).
+	URL string
+}
+
+// Root returns the first segment of the Namespace
+// (the user, the top level group or the organization).
+func (r *RemoteURL) Root() string {
+	if idx := strings.Index(r.Namespace, "/"); idx != -1 {
+		return r.Namespace[:idx]
+	}
+	return r.Namespace
+}
+
+// CommitURL returns the web page of the commit
+// (empty if the forge is unknown).
+func (r *RemoteURL) CommitURL(hash string) string {
+	switch r.Forge {
+	case GitHub, Gitea, Azure:
+		return r.URL + "/commit/" + hash
+	case GitLab:
+		return r.URL + "/-/commit/" + hash
+	case Bitbucket:
+		return r.URL + "/commits/" + hash
+	}
+	return ""
+}
+
+// TagURL returns the web page of the tag
+// (empty if the forge is unknown).
+func (r *RemoteURL) TagURL(tag string) string {
+	escaped := escapePath(strings.Split(tag, "/")...)
+
+	switch r.Forge {
+	case GitHub:
+		return r.URL + "/releases/tag/" + escaped
+	case GitLab:
+		return r.URL + "/-/tags/" + escaped
+	case Bitbucket:
+		return r.URL + "/src/" + escaped
+	case Gitea:
+		return r.URL + "/src/tag/" + escaped
+	case Azure:
+		return r.URL + "?version=GT" + url.QueryEscape(tag)
+	}
+	return ""
+}
+
+// ParseRemoteURL parses an https, ssh, git or scp-like ('git@host:path')
+// remote URL, dropping any embedded credentials.
+func ParseRemoteURL(raw string) (*RemoteURL, error) {
+	u, err := gitUrls.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "file" {
+		name := strings.TrimSuffix(path.Base(strings.TrimRight(u.Path, "/")), ".git")
+		return &RemoteURL{Name: name, URL: raw}, nil
+	}
+
+	scheme := "https"
+	host := u.Host
+	switch u.Scheme {
+	case "http", "https":
+		scheme = u.Scheme
+	default:
+		// ssh and git ports are not the web ones
+		host = u.Hostname()
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/"), "/")
+	if segments[0] == "" {
+		return nil, fmt.Errorf("invalid remote URL '%s': missing repository name", redact(raw))
+	}
+
+	res := &RemoteURL{Forge: detectForge(u.Hostname()), Host: host}
+
+	if res.Forge == Azure {
+		if err := parseAzure(res, segments); err != nil {
+			return nil, fmt.Errorf("invalid Azure DevOps URL '%s': %v", redact(raw), err)
+		}
+		return res, nil
+	}
+
+	res.Namespace = strings.Join(segments[:len(segments)-1], "/")
+	res.Name = segments[len(segments)-1]
+	res.URL = webURL(scheme, res.Host, segments...)
+
+	return res, nil
+}
+
+// detectForge guesses the forge from the host name.
+func detectForge(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case host == "dev.azure.com" || host == "ssh.dev.azure.com" ||
+		strings.HasSuffix(host, ".visualstudio.com"):
+		return Azure
+	case strings.Contains(host, "github"):
+		return GitHub
+	case strings.Contains(host, "gitlab"):
+		return GitLab
+	case strings.Contains(host, "bitbucket"):
+		return Bitbucket
+	case strings.Contains(host, "gitea") || host == "codeberg.org":
+		return Gitea
+	}
+	return ""
+}
+
+// parseAzure handles the Azure DevOps URL layouts:
+//
+//	https://dev.azure.com/{org}/{project}/_git/{repo}
+//	git@ssh.dev.azure.com:v3/{org}/{project}/{repo}
+//	https://{org}.visualstudio.com/[DefaultCollection/]{project}/_git/{repo}
+func parseAzure(res *RemoteURL, segments []string) error {
+	var org, project, repo string
+
+	host := strings.ToLower(res.Host)
+	switch {
+	case host == "ssh.dev.azure.com" && len(segments) == 4 && segments[0] == "v3":
+		org, project, repo = segments[1], segments[2], segments[3]
+	case host == "dev.azure.com" && len(segments) == 4 && segments[2] == "_git":
+		org, project, repo = segments[0], segments[1], segments[3]
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		if segments[0] == "DefaultCollection" {
+			segments = segments[1:]
+		}
+		// 'v3' is used by the ssh URLs (vs-ssh.visualstudio.com)
+		if len(segments) == 4 && segments[0] == "v3" {
+			org, project, repo = segments[1], segments[2], segments[3]
+			break
+		}
+		if len(segments) != 3 || segments[1] != "_git" {
+			return fmt.Errorf("unexpected path")
+		}
+		org, project, repo = strings.TrimSuffix(host, ".visualstudio.com"), segments[0], segments[2]
+	default:
+		return fmt.Errorf("unexpected path")
+	}
+
+	res.Host = "dev.azure.com"
+	res.Namespace = org + "/" + project
+	res.Name = repo
+	res.URL = webURL("https", res.Host, org, project, "_git", repo)
+
+	return nil
+}
+
+// webURL joins the (escaped) path segments to scheme and host.
+func webURL(scheme, host string, segments ...string) string {
+	return scheme + "://" + host + "/" + escapePath(segments...)
+}
+
+// escapePath escapes and joins the path segments.
+func escapePath(segments ...string) string {
+	res := make([]string, len(segments))
+	for i, el := range segments {
+		res[i] = url.PathEscape(el)
+	}
+	return strings.Join(res, "/")
+}
+
+// redact removes the password (if any) from a remote URL.
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
@@ -0,0 +1,90 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotDetected is returned by a Provider when
+// the path is not handled by it.
+var ErrNotDetected = errors.New("not detected")
+
+// Provider computes the REPO_* variables from a source
+// of version control metadata.
+type Provider interface {
+	// Name identifies the provider (i.e. 'git').
+	Name() string
+	// Metadata fills meta with the variables of the repository containing
+	// path, returning the warnings about the ones which cannot be computed.
+	// If path is not handled, the error satisfies errors.Is(err, ErrNotDetected).
+	Metadata(path string, opts Options, meta map[string]string) ([]Warning, error)
+}
+
+// DefaultProviders returns the built-in providers in detection
+// order: Git repositories, snapshots and CI environment variables.
+func DefaultProviders() []Provider {
+	return []Provider{&GitProvider{}, &SnapshotProvider{}, &CIProvider{}}
+}
+
+// ProviderByName returns the built-in provider with the specified name.
+func ProviderByName(name string) (Provider, error) {
+	names := []string{}
+	for _, el := range DefaultProviders() {
+		if el.Name() == name {
+			return el, nil
+		}
+		names = append(names, el.Name())
+	}
+
+	return nil, fmt.Errorf("unknown provider '%s' (available: %s)", name, strings.Join(names, ", "))
+}
+
+// CollectMetadata fills meta using the first provider handling path
+// (the default ones if none is specified) and sets REPO_PROVIDER.
+// It returns the warnings of the provider, or an error if no
+// provider handles path.
+func CollectMetadata(path string, opts Options, meta map[string]string, providers ...Provider) ([]Warning, error) {
+	if len(providers) == 0 {
+		providers = DefaultProviders()
+	}
+
+	reasons := make([]string, 0, len(providers))
+	for _, el := range providers {
+		warnings, err := el.Metadata(path, opts, meta)
+		if errors.Is(err, ErrNotDetected) {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		meta[RepoProvider] = el.Name()
+		return warnings, nil
+	}
+
+	return nil, fmt.Errorf("no version control metadata found: %s", strings.Join(reasons, "; "))
+}
+
+// notDetected wraps err so that errors.Is(err, ErrNotDetected) is true.
+func notDetected(provider string, err error) error {
+	return fmt.Errorf("%s: %w (%v)", provider, ErrNotDetected, err)
+}
+
+// GitProvider reads the metadata from a Git repository.
+type GitProvider struct{}
+
+// Name returns 'git'.
+func (p *GitProvider) Name() string {
+	return "git"
+}
+
+// Metadata calls CollectGitRepoMetadata.
+func (p *GitProvider) Metadata(path string, opts Options, meta map[string]string) ([]Warning, error) {
+	if _, _, err := detectGitRepo(path); err != nil {
+		return nil, notDetected(p.Name(), err)
+	}
+
+	return CollectGitRepoMetadata(path, opts, meta)
+}
@@ -0,0 +1,162 @@
+package vcs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a getenv func looking up the specified variables.
+func env(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func TestCollectMetadata(t *testing.T) {
+	clearCIEnv(t)
+
+	r := newTestRepo(t)
+	hash := r.commit("README.md", "hello", "first commit")
+
+	ci := &CIProvider{Getenv: env(map[string]string{"GITHUB_SHA": "0123456789abcdef"})}
+	providers := []Provider{&GitProvider{}, &SnapshotProvider{}, ci}
+
+	// the repository comes first
+	meta := map[string]string{}
+	_, err := CollectMetadata(filepath.Join(r.dir, "README.md"), Options{}, meta, providers...)
+	require.NoError(t, err)
+	assert.Equal(t, "git", meta[RepoProvider])
+	assert.Equal(t, hash.String(), meta[RepoCommit])
+
+	// the snapshot, taken before packaging, without the repository
+	src := t.TempDir()
+	require.NoError(t, NewSnapshot(meta).WriteFile(filepath.Join(src, SnapshotFile)))
+
+	meta = map[string]string{}
+	warnings, err := CollectMetadata(src, Options{}, meta, providers...)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "snapshot", meta[RepoProvider])
+	assert.Equal(t, hash.String(), meta[RepoCommit])
+	assert.Equal(t, "master", meta[RepoBranch])
+
+	// the CI variables (the snapshots in the parents are ignored)
+	sub := filepath.Join(src, "cmd")
+	require.NoError(t, os.Mkdir(sub, 0755))
+	meta = map[string]string{}
+	_, err = CollectMetadata(sub, Options{}, meta, providers...)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", meta[RepoProvider])
+	assert.Equal(t, "0123456", meta[RepoCommitShort])
+
+	// nothing
+	_, err = CollectMetadata(t.TempDir(), Options{}, map[string]string{}, &GitProvider{}, &SnapshotProvider{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no version control metadata found")
+}
+
+func TestSnapshot(t *testing.T) {
+	meta := map[string]string{
+		RepoCommit:   "0123456789abcdef",
+		RepoProvider: "git",
+		"TIMESTAMP":  "2021-07-26T14:00:00Z",
+	}
+
+	filename := filepath.Join(t.TempDir(), SnapshotFile)
+	require.NoError(t, NewSnapshot(meta).WriteFile(filename))
+
+	snap, err := ReadSnapshot(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "git", snap.Provider)
+	assert.Equal(t, map[string]string{RepoCommit: "0123456789abcdef"}, snap.Vars)
+	assert.False(t, snap.Created.IsZero())
+
+	// hand-written
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"vars": {"REPO_COMMIT": "abc"}}`), 0644))
+	snap, err = ReadSnapshot(filename)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{RepoCommit: "abc"}, snap.Vars)
+}
+
+func TestCIProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			name: "github tag",
+			env: map[string]string{
+				"GITHUB_SHA":        "0123456789abcdef",
+				"GITHUB_REF_TYPE":   "tag",
+				"GITHUB_REF_NAME":   "v1.2.3",
+				"GITHUB_REPOSITORY": "lucasepe/tbd",
+				"GITHUB_SERVER_URL": "https://github.com",
+			},
+			want: map[string]string{
+				RepoCommit:      "0123456789abcdef",
+				RepoCommitShort: "0123456",
+				RepoTag:         "v1.2.3",
+				RepoTagClean:    "1.2.3",
+				RepoURL:         "https://github.com/lucasepe/tbd",
+				RepoForge:       GitHub,
+				RepoHost:        "github.com",
+				RepoNamespace:   "lucasepe",
+				RepoRoot:        "lucasepe",
+				RepoName:        "tbd",
+				RepoCommitURL:   "https://github.com/lucasepe/tbd/commit/0123456789abcdef",
+				RepoTagURL:      "https://github.com/lucasepe/tbd/releases/tag/v1.2.3",
+			},
+		},
+		{
+			name: "gitlab branch",
+			env: map[string]string{
+				"CI_COMMIT_SHA":    "fedcba9876543210",
+				"CI_COMMIT_BRANCH": "feature/Login",
+				"CI_PROJECT_URL":   "https://gitlab.com/group/sub/project",
+			},
+			want: map[string]string{
+				RepoCommit:      "fedcba9876543210",
+				RepoCommitShort: "fedcba9",
+				RepoBranch:      "feature/Login",
+				RepoBranchSlug:  "feature-login",
+				RepoURL:         "https://gitlab.com/group/sub/project",
+				RepoForge:       GitLab,
+				RepoHost:        "gitlab.com",
+				RepoNamespace:   "group/sub",
+				RepoRoot:        "group",
+				RepoName:        "project",
+				RepoCommitURL:   "https://gitlab.com/group/sub/project/-/commit/fedcba9876543210",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := map[string]string{}
+			warnings, err := (&CIProvider{Getenv: env(tc.env)}).Metadata(".", Options{}, meta)
+			require.NoError(t, err)
+			assert.Empty(t, warnings)
+			assert.Equal(t, tc.want, meta)
+		})
+	}
+
+	// no commit
+	_, err := (&CIProvider{Getenv: env(nil)}).Metadata(".", Options{}, map[string]string{})
+	assert.True(t, errors.Is(err, ErrNotDetected))
+}
+
+func TestProviderByName(t *testing.T) {
+	p, err := ProviderByName("snapshot")
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot", p.Name())
+
+	_, err = ProviderByName("hg")
+	assert.EqualError(t, err, "unknown provider 'hg' (available: git, snapshot, ci)")
+}
@@ -0,0 +1,148 @@
+package vcs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semverRegex matches a semantic version (https://semver.org)
+// with an optional 'v' prefix.
+var semverRegex = regexp.MustCompile(`^[vV]?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Version is a semantic version.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Build      string
+}
+
+// ParseVersion parses a semantic version, optionally prefixed by 'v'.
+func ParseVersion(s string) (*Version, error) {
+	m := semverRegex.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("'%s' is not a semantic version", s)
+	}
+
+	res := &Version{Prerelease: m[4], Build: m[5]}
+	for i, dst := range []*uint64{&res.Major, &res.Minor, &res.Patch} {
+		n, err := strconv.ParseUint(m[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a semantic version: %w", s, err)
+		}
+		*dst = n
+	}
+
+	return res, nil
+}
+
+// String returns the version without the 'v' prefix.
+func (v *Version) String() string {
+	res := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		res += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		res += "+" + v.Build
+	}
+	return res
+}
+
+// Compare returns -1, 0 or +1 if v has lower, equal or higher
+// precedence than o; build metadata is not considered.
+func (v *Version) Compare(o *Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// a pre-release version has lower precedence
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(o.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(a)), uint64(len(b)))
+}
+
+// compareIdentifier compares two pre-release identifiers: numeric
+// ones numerically and with lower precedence than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// IncPatch returns the next patch version: the release itself
+// for a pre-release (1.2.0-rc.1 becomes 1.2.0), 1.2.1 for 1.2.0.
+func (v *Version) IncPatch() *Version {
+	if v.Prerelease != "" {
+		return &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	}
+	return &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// IncMinor returns the next minor version (1.3.0 for 1.2.5).
+func (v *Version) IncMinor() *Version {
+	return &Version{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// IncMajor returns the next major version (2.0.0 for 1.2.5).
+func (v *Version) IncMajor() *Version {
+	return &Version{Major: v.Major + 1}
+}
+
+// Inc returns the next version according to the bump level
+// (an identical copy without build metadata for BumpNone).
+func (v *Version) Inc(b Bump) *Version {
+	switch b {
+	case BumpMajor:
+		return v.IncMajor()
+	case BumpMinor:
+		return v.IncMinor()
+	case BumpPatch:
+		return v.IncPatch()
+	}
+	return &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch, Prerelease: v.Prerelease}
+}
@@ -0,0 +1,97 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Version
+	}{
+		{"1.2.3", &Version{Major: 1, Minor: 2, Patch: 3}},
+		{"v0.10.0", &Version{Minor: 10}},
+		{"V2.0.0-rc.1", &Version{Major: 2, Prerelease: "rc.1"}},
+		{"1.0.0-alpha+build.7", &Version{Major: 1, Prerelease: "alpha", Build: "build.7"}},
+		{"1.0.0+20210726", &Version{Major: 1, Build: "20210726"}},
+		{"1.2", nil},
+		{"01.2.3", nil},
+		{"1.2.3-", nil},
+		{"1.2.3-01", nil},
+		{"release-1", nil},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseVersion(tc.in)
+		if tc.want == nil {
+			assert.Error(t, err, tc.in)
+			continue
+		}
+		require.NoError(t, err, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	v, err := ParseVersion("v1.0.0-beta.2+exp.sha.5114f85")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0-beta.2+exp.sha.5114f85", v.String())
+}
+
+func TestVersionCompare(t *testing.T) {
+	// in ascending order of precedence (from semver.org)
+	all := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i := range all {
+		for j := range all {
+			a, err := ParseVersion(all[i])
+			require.NoError(t, err)
+			b, err := ParseVersion(all[j])
+			require.NoError(t, err)
+
+			want := compareUint(uint64(i), uint64(j))
+			assert.Equal(t, want, a.Compare(b), "%s vs %s", all[i], all[j])
+		}
+	}
+
+	a, _ := ParseVersion("1.0.0+build.1")
+	b, _ := ParseVersion("1.0.0+build.2")
+	assert.Equal(t, 0, a.Compare(b))
+}
+
+func TestVersionInc(t *testing.T) {
+	tests := []struct {
+		in                  string
+		patch, minor, major string
+	}{
+		{"1.2.3", "1.2.4", "1.3.0", "2.0.0"},
+		{"v0.9.0+build.1", "0.9.1", "0.10.0", "1.0.0"},
+		{"2.0.0-rc.2", "2.0.0", "2.1.0", "3.0.0"},
+	}
+
+	for _, tc := range tests {
+		v, err := ParseVersion(tc.in)
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.patch, v.IncPatch().String(), tc.in)
+		assert.Equal(t, tc.minor, v.IncMinor().String(), tc.in)
+		assert.Equal(t, tc.major, v.IncMajor().String(), tc.in)
+		assert.Equal(t, tc.patch, v.Inc(BumpPatch).String(), tc.in)
+	}
+}
@@ -0,0 +1,36 @@
+package vcs
+
+import (
+	"strings"
+)
+
+// maxSlugLength is the maximum length of a DNS label.
+const maxSlugLength = 63
+
+// Slug turns s in a string safe to be used as a DNS label
+// or a Docker tag: lower case alphanumerics and '-' only,
+// no leading or trailing '-' and at most 63 characters.
+func Slug(s string) string {
+	var sb strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+
+	res := sb.String()
+	if len(res) > maxSlugLength {
+		res = res[:maxSlugLength]
+	}
+
+	return strings.TrimRight(res, "-")
+}
@@ -0,0 +1,115 @@
+package vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SnapshotFile is the name of the file storing a snapshot of the
+// REPO_* variables, so that they are available also without the
+// repository (i.e. building from a source tarball).
+const SnapshotFile = ".tbd-vcs.json"
+
+// Snapshot is the content of a SnapshotFile.
+type Snapshot struct {
+	// Created is when the snapshot has been taken.
+	Created time.Time `json:"created"`
+	// Provider is the provider of the variables.
+	Provider string `json:"provider,omitempty"`
+	// Vars are the REPO_* variables.
+	Vars map[string]string `json:"vars"`
+}
+
+// NewSnapshot returns a snapshot of the REPO_* variables in meta.
+func NewSnapshot(meta map[string]string) *Snapshot {
+	res := &Snapshot{
+		Created:  time.Now().UTC().Truncate(time.Second),
+		Provider: meta[RepoProvider],
+		Vars:     map[string]string{},
+	}
+
+	for k, v := range meta {
+		if strings.HasPrefix(k, "REPO_") && k != RepoProvider {
+			res.Vars[k] = v
+		}
+	}
+
+	return res
+}
+
+// WriteFile stores the snapshot (as JSON) in filename.
+func (s *Snapshot) WriteFile(filename string) error {
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(buf, '\n'), 0644)
+}
+
+// ReadSnapshot reads a snapshot from filename.
+func ReadSnapshot(filename string) (*Snapshot, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Snapshot{}
+	if err := json.Unmarshal(buf, res); err != nil {
+		return nil, fmt.Errorf("invalid snapshot '%s': %w", filename, err)
+	}
+
+	return res, nil
+}
+
+// SnapshotProvider reads the metadata from the
+// SnapshotFile found in the specified directory.
+type SnapshotProvider struct{}
+
+// Name returns 'snapshot'.
+func (p *SnapshotProvider) Name() string {
+	return "snapshot"
+}
+
+// Metadata copies the snapshot variables (options are ignored).
+func (p *SnapshotProvider) Metadata(path string, opts Options, meta map[string]string) ([]Warning, error) {
+	filename, err := findSnapshot(path)
+	if err != nil {
+		return nil, notDetected(p.Name(), err)
+	}
+
+	snap, err := ReadSnapshot(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range snap.Vars {
+		meta[k] = v
+	}
+
+	return nil, nil
+}
+
+// findSnapshot looks for the SnapshotFile in path (or in its directory
+// if path is a file); parents are not searched, so that a stray
+// snapshot (i.e. in the home directory) is never picked up.
+func findSnapshot(path string) (string, error) {
+	if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+		path = filepath.Dir(path)
+	}
+
+	filename := filepath.Join(path, SnapshotFile)
+	if _, err := os.Stat(filename); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s not found in '%s'", SnapshotFile, path)
+		}
+		return "", err
+	}
+
+	return filename, nil
+}
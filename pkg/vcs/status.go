@@ -0,0 +1,53 @@
+package vcs
+
+import (
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// DirtyFiles returns the (sorted) paths of the files in the worktree
+// or in the index that differ from HEAD, untracked files included
+// unless ignoreUntracked is set, and the SnapshotFile excluded.
+// Bare repositories are never dirty.
+func DirtyFiles(repository *git.Repository, ignoreUntracked bool) ([]string, error) {
+	status, err := worktreeStatus(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	return dirtyFiles(status, ignoreUntracked), nil
+}
+
+// worktreeStatus returns the worktree status (empty for bare repositories).
+func worktreeStatus(repository *git.Repository) (git.Status, error) {
+	wt, err := repository.Worktree()
+	if err == git.ErrIsBareRepository {
+		return git.Status{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return wt.Status()
+}
+
+func dirtyFiles(status git.Status, ignoreUntracked bool) []string {
+	res := []string{}
+	for path, fs := range status {
+		if fs.Staging == git.Unmodified && fs.Worktree == git.Unmodified {
+			continue
+		}
+		if ignoreUntracked && fs.Worktree == git.Untracked {
+			continue
+		}
+		// written by 'tbd vars --snapshot' before packaging
+		if path == SnapshotFile {
+			continue
+		}
+		res = append(res, path)
+	}
+	sort.Strings(res)
+
+	return res
+}
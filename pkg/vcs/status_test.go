@@ -0,0 +1,57 @@
+package vcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirtyFiles(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("README.md", "hello", "first")
+	r.commit("docs/index.md", "index", "second")
+
+	repo, err := OpenGitRepo(r.dir)
+	require.NoError(t, err)
+
+	files, err := DirtyFiles(repo, false)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	r.write("README.md", "changed")
+	r.write("new.txt", "untracked")
+	r.write(SnapshotFile, "{}")
+	require.NoError(t, os.Remove(filepath.Join(r.dir, "docs", "index.md")))
+
+	files, err = DirtyFiles(repo, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "docs/index.md", "new.txt"}, files)
+
+	files, err = DirtyFiles(repo, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "docs/index.md"}, files)
+}
+
+func TestDirtyMetadata(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("README.md", "hello", "first")
+
+	meta := r.metadata()
+	assert.Equal(t, "false", meta[RepoDirty])
+	assert.Equal(t, "0", meta[RepoDirtyFiles])
+
+	r.write("new.txt", "untracked")
+	meta = r.metadata()
+	assert.Equal(t, "true", meta[RepoDirty])
+	assert.Equal(t, "1", meta[RepoDirtyFiles])
+	// untracked files don't make the version dirty
+	assert.NotContains(t, meta[RepoVersion], "-dirty")
+
+	meta = map[string]string{}
+	require.NoError(t, GitRepoMetadataWithOptions(r.dir, Options{IgnoreUntracked: true}, meta))
+	assert.Equal(t, "false", meta[RepoDirty])
+	assert.Equal(t, "0", meta[RepoDirtyFiles])
+}
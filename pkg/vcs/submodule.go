@@ -0,0 +1,74 @@
+package vcs
+
+import (
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Superproject is the repository a submodule belongs to.
+type Superproject struct {
+	// Repository is the superproject.
+	Repository *git.Repository
+	// Root is the worktree root of the superproject.
+	Root string
+	// Path is the (slash separated) path of the submodule in the superproject.
+	Path string
+}
+
+// SuperprojectFromGitRepo returns the superproject of the repository,
+// or nil if it is not a submodule (a repository just nested in another
+// one, without being listed in its .gitmodules, is not a submodule).
+func SuperprojectFromGitRepo(repository *git.Repository) (*Superproject, error) {
+	wt, err := repository.Worktree()
+	if err == git.ErrIsBareRepository {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root := wt.Filesystem.Root()
+	parent := filepath.Dir(root)
+	if parent == root {
+		return nil, nil
+	}
+
+	superRoot, _, err := detectGitRepo(parent)
+	if err != nil {
+		// not inside another repository
+		return nil, nil
+	}
+
+	rel, err := filepath.Rel(superRoot, root)
+	if err != nil {
+		return nil, nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	super, err := OpenGitRepo(superRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	superWt, err := super.Worktree()
+	if err == git.ErrIsBareRepository {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := superWt.Submodules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, el := range subs {
+		if filepath.ToSlash(filepath.Clean(el.Config().Path)) == rel {
+			return &Superproject{Repository: super, Root: superRoot, Path: rel}, nil
+		}
+	}
+
+	return nil, nil
+}
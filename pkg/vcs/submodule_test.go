@@ -0,0 +1,102 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkedWorktree(t *testing.T) {
+	r := newTestRepo(t)
+	head := r.commit("README.md", "hello", "first")
+	require.NoError(t, r.repo.Storer.SetReference(
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), head)))
+
+	// the layout of 'git worktree add ../wt feature'
+	wt := t.TempDir()
+	gitDir := filepath.Join(r.dir, ".git", "worktrees", "wt")
+	index, err := ioutil.ReadFile(filepath.Join(r.dir, ".git", "index"))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(gitDir, 0755))
+
+	for name, content := range map[string]string{
+		filepath.Join(gitDir, "HEAD"):      "ref: refs/heads/feature\n",
+		filepath.Join(gitDir, "commondir"): "../..\n",
+		filepath.Join(gitDir, "gitdir"):    filepath.Join(wt, ".git") + "\n",
+		filepath.Join(gitDir, "index"):     string(index),
+		filepath.Join(wt, ".git"):          "gitdir: " + gitDir + "\n",
+		filepath.Join(wt, "README.md"):     "hello",
+	} {
+		require.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+
+	dir, err := DetectGitPath(filepath.Join(wt, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, gitDir, dir)
+
+	meta := map[string]string{}
+	require.NoError(t, GitRepoMetadata(wt, meta))
+	assert.Equal(t, head.String(), meta[RepoCommit])
+	assert.Equal(t, "feature", meta[RepoBranch])
+	assert.Equal(t, "false", meta[RepoDirty])
+	assert.Equal(t, "false", meta[RepoSubmodule])
+}
+
+func TestSubmodule(t *testing.T) {
+	super := newTestRepo(t)
+	super.remote("origin", "git@github.com:pinco/main.git")
+	super.commit(".gitmodules", "[submodule \"lib\"]\n\tpath = libs/lib\n\turl = https://github.com/pinco/lib.git\n", "add lib")
+	superHead := super.commit("README.md", "main", "second")
+
+	// a nested repository which is not a submodule
+	nested := initTestRepo(t, filepath.Join(super.dir, "nested"))
+	nested.commit("README.md", "nested", "first")
+	assert.Equal(t, "false", nested.metadata()[RepoSubmodule])
+
+	// the layout of 'git submodule add': the git directory
+	// is in the superproject, pointed to by a '.git' file
+	sub := initTestRepo(t, filepath.Join(super.dir, "libs", "lib"))
+	subHead := sub.commit("lib.go", "package lib", "first")
+
+	modules := filepath.Join(super.dir, ".git", "modules")
+	require.NoError(t, os.MkdirAll(modules, 0755))
+	require.NoError(t, os.Rename(filepath.Join(sub.dir, ".git"), filepath.Join(modules, "lib")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sub.dir, ".git"), []byte("gitdir: ../../.git/modules/lib\n"), 0644))
+
+	// as written by git
+	cfg := config.NewConfig()
+	cfg.Core.Worktree = "../../../libs/lib"
+	buf, err := cfg.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modules, "lib", "config"), buf, 0644))
+
+	dir, err := DetectGitPath(sub.dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(modules, "lib"), dir)
+
+	meta := map[string]string{}
+	require.NoError(t, GitRepoMetadata(filepath.Join(sub.dir, "lib.go"), meta))
+	assert.Equal(t, subHead.String(), meta[RepoCommit])
+	assert.Equal(t, "false", meta[RepoDirty])
+	assert.Equal(t, "true", meta[RepoSubmodule])
+	assert.Equal(t, "libs/lib", meta[RepoSubmodulePath])
+	assert.Equal(t, superHead.String(), meta[RepoSuperCommit])
+	assert.Equal(t, "https://github.com/pinco/main", meta[RepoSuperURL])
+
+	// a plain repository at the submodule path works the same
+	require.NoError(t, os.Remove(filepath.Join(sub.dir, ".git")))
+	require.NoError(t, os.Rename(filepath.Join(modules, "lib"), filepath.Join(sub.dir, ".git")))
+
+	meta = map[string]string{}
+	require.NoError(t, GitRepoMetadata(sub.dir, meta))
+	assert.Equal(t, "true", meta[RepoSubmodule])
+	assert.Equal(t, "libs/lib", meta[RepoSubmodulePath])
+
+	assert.Equal(t, "false", super.metadata()[RepoSubmodule])
+}
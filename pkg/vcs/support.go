@@ -1,62 +1,421 @@
 package vcs
 
 import (
-	"net/url"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 const (
-	RepoCommit   = "REPO_COMMIT"
-	RepoTag      = "REPO_TAG"
-	RepoTagClean = "REPO_TAG_CLEAN"
-	RepoURL      = "REPO_URL"
-	RepoHost     = "REPO_HOST"
-	RepoName     = "REPO_NAME"
-	RepoRoot     = "REPO_ROOT"
+	RepoCommit          = "REPO_COMMIT"
+	RepoCommitShort     = "REPO_COMMIT_SHORT"
+	RepoCommitAuthor    = "REPO_COMMIT_AUTHOR"
+	RepoCommitEmail     = "REPO_COMMIT_AUTHOR_EMAIL"
+	RepoCommitDate      = "REPO_COMMIT_DATE"
+	RepoCommitTimestamp = "REPO_COMMIT_TIMESTAMP"
+	RepoCommitSubject   = "REPO_COMMIT_SUBJECT"
+	RepoCommitMessage   = "REPO_COMMIT_MESSAGE"
+	RepoCommitsSinceTag = "REPO_COMMITS_SINCE_TAG"
+	RepoVersion         = "REPO_VERSION"
+	RepoDirty           = "REPO_DIRTY"
+	RepoDirtyFiles      = "REPO_DIRTY_FILES"
+	RepoBranch          = "REPO_BRANCH"
+	RepoBranchSlug      = "REPO_BRANCH_SLUG"
+	RepoTag             = "REPO_TAG"
+	RepoTagClean        = "REPO_TAG_CLEAN"
+	RepoTagMajor        = "REPO_TAG_MAJOR"
+	RepoTagMinor        = "REPO_TAG_MINOR"
+	RepoTagPatch        = "REPO_TAG_PATCH"
+	RepoTagPrerelease   = "REPO_TAG_PRERELEASE"
+	RepoTagBuild        = "REPO_TAG_BUILD"
+	RepoNextPatch       = "REPO_NEXT_PATCH"
+	RepoNextMinor       = "REPO_NEXT_MINOR"
+	RepoNextMajor       = "REPO_NEXT_MAJOR"
+	RepoNextVersion     = "REPO_NEXT_VERSION"
+	RepoRemote          = "REPO_REMOTE"
+	RepoRemotes         = "REPO_REMOTES"
+	RepoSubmodule       = "REPO_SUBMODULE"
+	RepoSubmodulePath   = "REPO_SUBMODULE_PATH"
+	RepoSuperCommit     = "REPO_SUPERPROJECT_COMMIT"
+	RepoSuperURL        = "REPO_SUPERPROJECT_URL"
+	RepoURL             = "REPO_URL"
+	RepoForge           = "REPO_FORGE"
+	RepoNamespace       = "REPO_NAMESPACE"
+	RepoCommitURL       = "REPO_COMMIT_URL"
+	RepoTagURL          = "REPO_TAG_URL"
+	RepoHost            = "REPO_HOST"
+	RepoName            = "REPO_NAME"
+	RepoRoot            = "REPO_ROOT"
+	RepoProvider        = "REPO_PROVIDER"
+
+	RepoChangedBase       = "REPO_CHANGED_BASE"
+	RepoChangedPaths      = "REPO_CHANGED_PATHS"
+	RepoChangedComponents = "REPO_CHANGED_COMPONENTS"
 )
 
+// Options tunes the computation of the repository metadata.
+type Options struct {
+	// IgnoreUntracked doesn't consider untracked files as changes.
+	IgnoreUntracked bool
+	// TagPattern, if not empty, is the glob the tags must match
+	// (i.e. 'api/v*' to version each component of a monorepo).
+	TagPattern string
+	// ConventionalCommits infers the next version from the
+	// Conventional Commit messages since the latest tag.
+	ConventionalCommits bool
+	// Remote is the remote the URL variables are computed from
+	// (if empty 'origin', 'upstream' or the first one).
+	Remote string
+	// Base is the revision the changed paths are computed
+	// from (if empty the latest tag).
+	Base string
+	// Components are flagged (see ComponentVar) if they
+	// have changed paths.
+	Components []Component
+}
+
+// Warning reports why some variables cannot be computed (or are empty).
+type Warning struct {
+	// Topic is what failed (i.e. 'tag' or 'remote').
+	Topic string
+	// Vars are the affected variables.
+	Vars []string
+	// Err is the cause.
+	Err error
+}
+
+func (w Warning) Error() string {
+	return fmt.Sprintf("%s: %v", w.Topic, w.Err)
+}
+
+func (w Warning) Unwrap() error {
+	return w.Err
+}
+
 func GitRepoMetadata(path string, meta map[string]string) error {
+	return GitRepoMetadataWithOptions(path, Options{}, meta)
+}
+
+// GitRepoMetadataWithOptions is CollectGitRepoMetadata ignoring the warnings.
+func GitRepoMetadataWithOptions(path string, opts Options, meta map[string]string) error {
+	_, err := CollectGitRepoMetadata(path, opts, meta)
+	return err
+}
+
+// CollectGitRepoMetadata computes the variables describing the repository
+// containing path. Each group of variables is computed independently:
+// the ones which cannot be computed are reported as warnings, an error
+// is returned only if the repository cannot be opened.
+func CollectGitRepoMetadata(path string, opts Options, meta map[string]string) ([]Warning, error) {
 	repo, err := OpenGitRepo(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	commit, err := CurrentCommitFromGitRepo(repo)
+	c := &collector{repo: repo, opts: opts, meta: meta}
+
+	head := c.commit()
+	status := c.status()
+	latest := c.tags(head, status)
+	c.changes(head, latest)
+	c.branch()
+	c.submodule()
+	c.remote(head, latest)
+
+	return c.warnings, nil
+}
+
+// collector computes the repository variables collecting the warnings.
+type collector struct {
+	repo     *git.Repository
+	opts     Options
+	meta     map[string]string
+	warnings []Warning
+}
+
+func (c *collector) warn(topic string, err error, vars ...string) {
+	c.warnings = append(c.warnings, Warning{Topic: topic, Vars: vars, Err: err})
+}
+
+// commit exports the HEAD commit variables, returning the commit.
+func (c *collector) commit() *object.Commit {
+	head, err := HeadCommitFromGitRepo(c.repo)
 	if err != nil {
-		return err
+		c.warn("commit", err, RepoCommit, RepoCommitShort, RepoCommitAuthor, RepoCommitEmail,
+			RepoCommitDate, RepoCommitTimestamp, RepoCommitSubject, RepoCommitMessage, RepoCommitURL,
+			RepoVersion, RepoCommitsSinceTag, RepoTag, RepoTagURL, RepoNextPatch, RepoNextMinor, RepoNextMajor,
+			RepoChangedBase, RepoChangedPaths, RepoChangedComponents)
+		return nil
 	}
-	meta[RepoCommit] = commit
 
-	tag, err := LatestTagFromGitRepo(repo)
+	c.meta[RepoCommit] = head.Hash.String()
+	c.meta[RepoCommitShort] = head.Hash.String()[:shortHashLength]
+	c.meta[RepoCommitAuthor] = head.Author.Name
+	c.meta[RepoCommitEmail] = head.Author.Email
+	c.meta[RepoCommitDate] = head.Committer.When.UTC().Format(time.RFC3339)
+	c.meta[RepoCommitTimestamp] = strconv.FormatInt(head.Committer.When.Unix(), 10)
+	c.meta[RepoCommitSubject] = CommitSubject(head)
+	c.meta[RepoCommitMessage] = strings.TrimSpace(head.Message)
+
+	return head
+}
+
+// changes exports the paths (and the components) changed
+// since the base revision or the latest tag.
+func (c *collector) changes(head *object.Commit, latest *Tag) {
+	if head == nil {
+		return
+	}
+
+	vars := []string{RepoChangedBase, RepoChangedPaths, RepoChangedComponents}
+	for _, el := range c.opts.Components {
+		vars = append(vars, ComponentVar(el.Name))
+	}
+
+	var base *object.Commit
+	switch {
+	case c.opts.Base != "":
+		hash, err := c.repo.ResolveRevision(plumbing.Revision(c.opts.Base))
+		if err != nil {
+			c.warn("changes", fmt.Errorf("cannot resolve '%s': %w", c.opts.Base, err), vars...)
+			return
+		}
+		if base, err = c.repo.CommitObject(*hash); err != nil {
+			c.warn("changes", err, vars...)
+			return
+		}
+		c.meta[RepoChangedBase] = c.opts.Base
+	case latest != nil:
+		base = latest.Commit
+		c.meta[RepoChangedBase] = latest.Name
+	default:
+		// no tags: all the files are new
+		c.meta[RepoChangedBase] = ""
+	}
+
+	files, err := ChangedPaths(head, base)
 	if err != nil {
-		return err
+		c.warn("changes", err, vars[1:]...)
+		return
+	}
+	c.meta[RepoChangedPaths] = strings.Join(files, ",")
+
+	changed := ChangedComponents(c.opts.Components, files)
+	c.meta[RepoChangedComponents] = strings.Join(changed, ",")
+	for _, el := range c.opts.Components {
+		c.meta[ComponentVar(el.Name)] = "false"
+	}
+	for _, el := range changed {
+		c.meta[ComponentVar(el)] = "true"
+	}
+}
+
+// status exports the worktree status variables, returning the status.
+func (c *collector) status() git.Status {
+	status, err := worktreeStatus(c.repo)
+	if err != nil {
+		c.warn("status", err, RepoDirty, RepoDirtyFiles)
+		return nil
+	}
+
+	dirty := dirtyFiles(status, c.opts.IgnoreUntracked)
+	c.meta[RepoDirty] = strconv.FormatBool(len(dirty) > 0)
+	c.meta[RepoDirtyFiles] = strconv.Itoa(len(dirty))
+
+	return status
+}
+
+// tags exports the tag and version variables, returning the latest tag.
+func (c *collector) tags(head *object.Commit, status git.Status) *Tag {
+	if head == nil {
+		return nil
+	}
+
+	tags, err := ReachableTags(c.repo, c.opts.TagPattern)
+	if err != nil {
+		c.warn("tag", err, RepoTag, RepoTagClean, RepoTagURL, RepoVersion, RepoCommitsSinceTag)
+		return nil
+	}
+
+	desc, err := describe(c.repo, tags)
+	if err != nil {
+		c.warn("version", err, RepoVersion, RepoCommitsSinceTag)
+	} else {
+		// like 'git describe --dirty', only tracked files matter
+		desc.Dirty = len(dirtyFiles(status, true)) > 0
+		c.meta[RepoCommitsSinceTag] = strconv.Itoa(desc.Distance)
+		c.meta[RepoVersion] = desc.String()
+	}
+
+	var tag string
+	latest := LatestTag(tags)
+	if latest != nil {
+		tag = latest.Name
+	} else if c.opts.TagPattern != "" {
+		c.warn("tag", fmt.Errorf("no tags matching '%s' reachable from HEAD", c.opts.TagPattern), RepoTag, RepoTagClean, RepoTagURL)
+	} else {
+		c.warn("tag", errors.New("no tags reachable from HEAD"), RepoTag, RepoTagClean, RepoTagURL)
 	}
 	idx := strings.LastIndex(tag, "/")
 	if idx != -1 {
 		tag = tag[idx+1:]
 	}
-	meta[RepoTag] = tag
+	c.meta[RepoTag] = tag
 
 	if strings.HasPrefix(tag, "v") {
-		meta[RepoTagClean] = tag[1:]
+		c.meta[RepoTagClean] = tag[1:]
 	}
 
-	repoURL, err := GitRepoURL(repo)
+	if err := versionMetadata(head, latest, c.opts, c.meta); err != nil {
+		c.warn("next version", err, RepoNextVersion)
+	}
+
+	return latest
+}
+
+// branch exports the branch variables.
+func (c *collector) branch() {
+	branch, err := CurrentBranchFromGitRepo(c.repo)
 	if err != nil {
-		return err
+		c.warn("branch", err, RepoBranch, RepoBranchSlug)
+		return
+	}
+	if branch == "" {
+		// detached HEAD, i.e. in CI pipelines
+		branch = branchFromEnv(os.Getenv)
+	}
+	if branch == "" {
+		c.warn("branch", errors.New("HEAD is detached and no CI variable names the branch"), RepoBranch, RepoBranchSlug)
+		return
 	}
-	meta[RepoURL] = repoURL
 
-	if u, err := url.Parse(repoURL); err == nil {
-		idx := strings.Index(u.Path[1:], "/")
-		if idx != -1 {
-			meta[RepoRoot] = u.Path[1 : idx+1]
+	c.meta[RepoBranch] = branch
+	c.meta[RepoBranchSlug] = Slug(branch)
+}
+
+// submodule exports the submodule variables.
+func (c *collector) submodule() {
+	if err := submoduleMetadata(c.repo, c.meta); err != nil {
+		c.warn("submodule", err, RepoSubmodule, RepoSubmodulePath, RepoSuperCommit, RepoSuperURL)
+	}
+}
+
+// remote exports the remote URL variables.
+func (c *collector) remote(head *object.Commit, latest *Tag) {
+	vars := []string{RepoRemote, RepoURL, RepoForge, RepoHost, RepoNamespace,
+		RepoRoot, RepoName, RepoCommitURL, RepoTagURL}
+
+	c.meta[RepoURL] = ""
+	c.meta[RepoName] = ""
+
+	cfg, err := c.repo.Config()
+	if err != nil {
+		c.warn("remote", err, append([]string{RepoRemotes}, vars...)...)
+		return
+	}
+	c.meta[RepoRemotes] = strings.Join(RemoteNames(cfg), ",")
+
+	remote, err := SelectRemote(c.repo, c.opts.Remote)
+	if err != nil {
+		c.warn("remote", err, vars...)
+		return
+	}
+	if remote == nil {
+		c.warn("remote", errors.New("no remotes configured"), vars...)
+		return
+	}
+	c.meta[RepoRemote] = remote.Name
+
+	info, err := parseRemote(remote)
+	if err != nil {
+		c.warn("remote", err, vars[1:]...)
+		return
+	}
+	if info == nil {
+		c.warn("remote", fmt.Errorf("remote '%s' has no URL", remote.Name), vars[1:]...)
+		return
+	}
+
+	c.meta[RepoURL] = info.URL
+	c.meta[RepoName] = info.Name
+	if info.Host != "" {
+		c.meta[RepoHost] = info.Host
+		c.meta[RepoRoot] = info.Root()
+		c.meta[RepoNamespace] = info.Namespace
+	}
+	if info.Forge != "" {
+		c.meta[RepoForge] = info.Forge
+		if head != nil {
+			c.meta[RepoCommitURL] = info.CommitURL(head.Hash.String())
 		}
+		if latest != nil {
+			c.meta[RepoTagURL] = info.TagURL(latest.Name)
+		}
+	}
+}
+
+// versionMetadata exports the semantic version components of the
+// latest tag and the next versions (starting from 0.0.0 without tags).
+func versionMetadata(head *object.Commit, latest *Tag, opts Options, meta map[string]string) error {
+	cur := &Version{}
+	var since *object.Commit
+	if latest != nil && latest.Version != nil {
+		cur, since = latest.Version, latest.Commit
+
+		meta[RepoTagMajor] = strconv.FormatUint(cur.Major, 10)
+		meta[RepoTagMinor] = strconv.FormatUint(cur.Minor, 10)
+		meta[RepoTagPatch] = strconv.FormatUint(cur.Patch, 10)
+		meta[RepoTagPrerelease] = cur.Prerelease
+		meta[RepoTagBuild] = cur.Build
+	}
+
+	meta[RepoNextPatch] = cur.IncPatch().String()
+	meta[RepoNextMinor] = cur.IncMinor().String()
+	meta[RepoNextMajor] = cur.IncMajor().String()
+
+	if opts.ConventionalCommits {
+		bump, err := ConventionalBump(head, since)
+		if err != nil {
+			return err
+		}
+		meta[RepoNextVersion] = cur.Inc(bump).String()
+	}
+
+	return nil
+}
 
-		meta[RepoHost] = u.Host
+// submoduleMetadata reports whether the repository is a submodule
+// and, if so, describes its superproject.
+func submoduleMetadata(repo *git.Repository, meta map[string]string) error {
+	super, err := SuperprojectFromGitRepo(repo)
+	if err != nil {
+		return err
 	}
 
-	meta[RepoName] = repoURL[strings.LastIndex(repoURL, "/")+1:]
+	meta[RepoSubmodule] = strconv.FormatBool(super != nil)
+	if super == nil {
+		return nil
+	}
+	meta[RepoSubmodulePath] = super.Path
+
+	commit, err := CurrentCommitFromGitRepo(super.Repository)
+	if err != nil {
+		return err
+	}
+	meta[RepoSuperCommit] = commit
+
+	superURL, err := GitRepoURL(super.Repository)
+	if err != nil {
+		return err
+	}
+	meta[RepoSuperURL] = superURL
 
 	return nil
 }
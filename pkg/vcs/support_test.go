@@ -0,0 +1,220 @@
+package vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRepo is a throwaway (non bare) repository.
+type testRepo struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+	wt   *git.Worktree
+	when time.Time
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	return initTestRepo(t, t.TempDir())
+}
+
+// initTestRepo creates a repository in the specified directory.
+func initTestRepo(t *testing.T, dir string) *testRepo {
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	return &testRepo{
+		t:    t,
+		dir:  dir,
+		repo: repo,
+		wt:   wt,
+		when: time.Date(2021, time.July, 26, 14, 0, 0, 0, time.UTC),
+	}
+}
+
+// write creates (or overwrites) a file in the worktree.
+func (r *testRepo) write(name, content string) {
+	filename := filepath.Join(r.dir, filepath.FromSlash(name))
+	require.NoError(r.t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.NoError(r.t, ioutil.WriteFile(filename, []byte(content), 0644))
+}
+
+// commit writes and commits the specified file, one minute after the previous commit.
+func (r *testRepo) commit(name, content, msg string) plumbing.Hash {
+	r.write(name, content)
+
+	_, err := r.wt.Add(name)
+	require.NoError(r.t, err)
+
+	r.when = r.when.Add(time.Minute)
+	sig := &object.Signature{Name: "Pinco Pallo", Email: "pinco.pallo@example.com", When: r.when}
+	hash, err := r.wt.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig})
+	require.NoError(r.t, err)
+
+	return hash
+}
+
+// checkout switches to the specified branch (created if missing).
+func (r *testRepo) checkout(branch string) {
+	name := plumbing.NewBranchReferenceName(branch)
+	_, err := r.repo.Reference(name, false)
+	require.NoError(r.t, r.wt.Checkout(&git.CheckoutOptions{Branch: name, Create: err != nil}))
+}
+
+// detach points HEAD directly to the specified commit.
+func (r *testRepo) detach(hash plumbing.Hash) {
+	require.NoError(r.t, r.wt.Checkout(&git.CheckoutOptions{Hash: hash}))
+}
+
+// metadata returns the variables computed for the repository.
+func (r *testRepo) metadata() map[string]string {
+	meta := map[string]string{}
+	require.NoError(r.t, GitRepoMetadata(r.dir, meta))
+	return meta
+}
+
+func TestBranchMetadata(t *testing.T) {
+	clearCIEnv(t)
+
+	r := newTestRepo(t)
+	first := r.commit("README.md", "hello", "first")
+
+	meta := r.metadata()
+	assert.Equal(t, "master", meta[RepoBranch])
+	assert.Equal(t, "master", meta[RepoBranchSlug])
+
+	// a second branch at the same commit
+	r.checkout("Feature/JIRA-42_new_UI")
+	meta = r.metadata()
+	assert.Equal(t, "Feature/JIRA-42_new_UI", meta[RepoBranch])
+	assert.Equal(t, "feature-jira-42-new-ui", meta[RepoBranchSlug])
+
+	r.detach(first)
+	meta = r.metadata()
+	assert.NotContains(t, meta, RepoBranch)
+	assert.NotContains(t, meta, RepoBranchSlug)
+
+	os.Setenv("CI_COMMIT_REF_NAME", "release/1.x")
+	meta = r.metadata()
+	assert.Equal(t, "release/1.x", meta[RepoBranch])
+	assert.Equal(t, "release-1-x", meta[RepoBranchSlug])
+}
+
+func TestBranchFromEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"GITHUB_REF_NAME": "main", "GITHUB_REF_TYPE": "branch"}, "main"},
+		{map[string]string{"GITHUB_REF_NAME": "v1.0.0", "GITHUB_REF_TYPE": "tag"}, ""},
+		{map[string]string{"GITHUB_REF_NAME": "42/merge", "GITHUB_REF_TYPE": "branch", "GITHUB_HEAD_REF": "fix/bug"}, "fix/bug"},
+		{map[string]string{"CI_COMMIT_REF_NAME": "v1.0.0", "CI_COMMIT_TAG": "v1.0.0"}, ""},
+		{map[string]string{"CI_COMMIT_REF_NAME": "develop"}, "develop"},
+		{map[string]string{"BUILD_SOURCEBRANCH": "refs/heads/feature/x"}, "feature/x"},
+		{map[string]string{"BUILD_SOURCEBRANCH": "refs/tags/v1"}, ""},
+		{map[string]string{"GIT_BRANCH": "origin/main"}, "main"},
+		{map[string]string{"CIRCLE_BRANCH": "staging"}, "staging"},
+	}
+
+	for i, tc := range tests {
+		got := branchFromEnv(func(k string) string { return tc.env[k] })
+		assert.Equal(t, tc.want, got, i)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"main", "main"},
+		{"feature/JIRA-42_new UI", "feature-jira-42-new-ui"},
+		{"--weird--/--name--", "weird-name"},
+		{"release/v1.2.3", "release-v1-2-3"},
+		{"", ""},
+		{"a/very-long-branch-name-that-does-not-fit-in-a-dns-label-at-all-really", "a-very-long-branch-name-that-does-not-fit-in-a-dns-label-at-all"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, Slug(tc.in), tc.in)
+	}
+}
+
+// clearCIEnv unsets (for the duration of the test)
+// the CI variables used to guess the branch name.
+func clearCIEnv(t *testing.T) {
+	for _, name := range []string{
+		"GITHUB_HEAD_REF", "GITHUB_REF_TYPE", "GITHUB_REF_NAME",
+		"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH", "CI_COMMIT_TAG", "CI_COMMIT_REF_NAME",
+		"SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCH",
+		"BITBUCKET_BRANCH", "BUILDKITE_BRANCH", "CIRCLE_BRANCH", "DRONE_SOURCE_BRANCH",
+		"TRAVIS_PULL_REQUEST_BRANCH", "TRAVIS_BRANCH", "CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH",
+	} {
+		value, ok := os.LookupEnv(name)
+		os.Unsetenv(name)
+
+		name := name
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func TestCollectGitRepoMetadata(t *testing.T) {
+	clearCIEnv(t)
+
+	// no commits yet: the remote is still described
+	r := newTestRepo(t)
+	r.remote("origin", "git@github.com:lucasepe/tbd.git")
+
+	meta := map[string]string{}
+	warnings, err := CollectGitRepoMetadata(r.dir, Options{}, meta)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "commit", warnings[0].Topic)
+	assert.Contains(t, warnings[0].Vars, RepoCommit)
+	assert.Contains(t, warnings[0].Vars, RepoTag)
+	assert.Equal(t, "https://github.com/lucasepe/tbd", meta[RepoURL])
+	assert.Equal(t, "tbd", meta[RepoName])
+	assert.Equal(t, "master", meta[RepoBranch])
+	assert.NotContains(t, meta, RepoCommit)
+
+	// no tags
+	first := r.commit("README.md", "hello", "first")
+	meta = map[string]string{}
+	warnings, err = CollectGitRepoMetadata(r.dir, Options{TagPattern: "api/*"}, meta)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.EqualError(t, warnings[0], "tag: no tags matching 'api/*' reachable from HEAD")
+	assert.Equal(t, first.String(), meta[RepoCommit])
+	assert.Equal(t, first.String()[:7], meta[RepoVersion])
+	assert.Equal(t, "https://github.com/lucasepe/tbd/commit/"+first.String(), meta[RepoCommitURL])
+
+	// detached HEAD outside of CI
+	r.tag("v1.0.0", first, "")
+	r.detach(first)
+	warnings, err = CollectGitRepoMetadata(r.dir, Options{}, map[string]string{})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "branch", warnings[0].Topic)
+
+	_, err = CollectGitRepoMetadata(t.TempDir(), Options{}, map[string]string{})
+	assert.Error(t, err)
+}
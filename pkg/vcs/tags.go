@@ -0,0 +1,125 @@
+package vcs
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Tag is a (lightweight or annotated) tag pointing to a commit.
+type Tag struct {
+	// Name is the short name (i.e. 'v1.2.0' or 'api/v1.2.0').
+	Name string
+	// Commit is the tagged commit.
+	Commit *object.Commit
+	// Version is the semantic version of the tag (the
+	// part after the last '/'), nil if it is not one.
+	Version *Version
+}
+
+// ReachableTags returns the tags pointing to HEAD or to one of its
+// ancestors; if pattern is not empty, only the tags whose name matches
+// the glob (i.e. 'api/v*') are returned. Tags pointing to trees or blobs
+// are ignored.
+func ReachableTags(repository *git.Repository, pattern string) ([]Tag, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid tag pattern '%s': %w", pattern, err)
+	}
+
+	head, err := HeadCommitFromGitRepo(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := map[plumbing.Hash]*object.Commit{}
+	err = object.NewCommitPreorderIter(head, nil, nil).ForEach(func(c *object.Commit) error {
+		ancestors[c.Hash] = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tagRefs, err := repository.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	res := []Tag{}
+	err = tagRefs.ForEach(func(tagRef *plumbing.Reference) error {
+		name := tagRef.Name().Short()
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, name); !ok {
+				return nil
+			}
+		}
+
+		hash := tagRef.Hash()
+		if tag, err := repository.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				// not pointing to a commit
+				return nil
+			}
+			hash = commit.Hash
+		}
+
+		commit, ok := ancestors[hash]
+		if !ok {
+			return nil
+		}
+
+		el := Tag{Name: name, Commit: commit}
+		el.Version, _ = ParseVersion(name[strings.LastIndex(name, "/")+1:])
+		res = append(res, el)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// LatestTag returns the tag with the highest semantic version; if none
+// of the tags is a semantic version, the one with the newest commit
+// (by committer date) is returned. It returns nil if tags is empty.
+func LatestTag(tags []Tag) *Tag {
+	var res *Tag
+	for i := range tags {
+		if res == nil || newerTag(&tags[i], res) {
+			res = &tags[i]
+		}
+	}
+
+	return res
+}
+
+// newerTag reports whether a ranks higher than b.
+func newerTag(a, b *Tag) bool {
+	switch {
+	case a.Version != nil && b.Version != nil:
+		if c := a.Version.Compare(b.Version); c != 0 {
+			return c > 0
+		}
+	case a.Version != nil:
+		return true
+	case b.Version != nil:
+		return false
+	default:
+		if !a.Commit.Committer.When.Equal(b.Commit.Committer.When) {
+			return a.Commit.Committer.When.After(b.Commit.Committer.When)
+		}
+	}
+
+	return a.Name > b.Name
+}
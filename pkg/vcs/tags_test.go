@@ -0,0 +1,97 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReachableTags(t *testing.T) {
+	r := newTestRepo(t)
+
+	first := r.commit("README.md", "one", "first")
+	r.tag("v1.0.0", first, "")
+	r.tag("api/v0.1.0", first, "api release")
+
+	// a tag pointing to a blob
+	blob := r.repo.Storer.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	hash, err := r.repo.Storer.SetEncodedObject(blob)
+	require.NoError(t, err)
+	r.tag("blob", hash, "")
+
+	r.checkout("other")
+	other := r.commit("other.txt", "other", "other")
+	r.tag("v9.0.0", other, "")
+
+	r.checkout("master")
+	second := r.commit("README.md", "two", "second")
+	r.tag("v1.1.0-rc.1", second, "release candidate")
+
+	names := func(pattern string) []string {
+		tags, err := ReachableTags(r.repo, pattern)
+		require.NoError(t, err)
+
+		res := []string{}
+		for _, el := range tags {
+			res = append(res, el.Name)
+		}
+		return res
+	}
+
+	assert.Equal(t, []string{"api/v0.1.0", "v1.0.0", "v1.1.0-rc.1"}, names(""))
+	assert.Equal(t, []string{"api/v0.1.0"}, names("api/v*"))
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0-rc.1"}, names("v*"))
+
+	_, err = ReachableTags(r.repo, "[")
+	assert.Error(t, err)
+}
+
+func TestLatestTag(t *testing.T) {
+	r := newTestRepo(t)
+
+	first := r.commit("README.md", "one", "first")
+	r.tag("v1.10.0", first, "")
+	r.tag("stable", first, "")
+
+	// newer commit, lower version
+	second := r.commit("README.md", "two", "second")
+	r.tag("v1.9.0", second, "")
+	r.tag("nightly", second, "")
+
+	tags, err := ReachableTags(r.repo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.10.0", LatestTag(tags).Name)
+
+	// without semantic versions, the newest commit wins
+	tags, err = ReachableTags(r.repo, "[ns]*")
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", LatestTag(tags).Name)
+
+	assert.Nil(t, LatestTag(nil))
+
+	name, err := LatestTagFromGitRepo(r.repo)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.10.0", name)
+}
+
+func TestTagMetadata(t *testing.T) {
+	r := newTestRepo(t)
+
+	first := r.commit("README.md", "one", "first")
+	r.tag("v2.0.0", first, "")
+	r.tag("api/v0.3.0", first, "")
+	r.commit("README.md", "two", "second")
+
+	meta := map[string]string{}
+	require.NoError(t, GitRepoMetadataWithOptions(r.dir, Options{TagPattern: "api/v*"}, meta))
+	assert.Equal(t, "v0.3.0", meta[RepoTag])
+	assert.Equal(t, "0.3.0", meta[RepoTagClean])
+	assert.Contains(t, meta[RepoVersion], "api/v0.3.0-1-g")
+
+	meta = r.metadata()
+	assert.Equal(t, "v2.0.0", meta[RepoTag])
+	assert.Contains(t, meta[RepoVersion], "v2.0.0-1-g")
+}
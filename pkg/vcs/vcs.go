@@ -2,16 +2,17 @@ package vcs
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/pkg/errors"
-
-	gitUrls "github.com/whilp/git-urls"
 )
 
 func InitGitRepo(path string, isBare bool) (*git.Repository, error) {
@@ -25,13 +26,17 @@ func InitGitRepo(path string, isBare bool) (*git.Repository, error) {
 	return repo, nil
 }
 
+// OpenGitRepo opens the repository containing path: a plain
+// repository, a bare one, a linked worktree or a submodule.
 func OpenGitRepo(path string) (*git.Repository, error) {
-	path, err := DetectGitPath(path)
+	path, _, err := detectGitRepo(path)
 	if err != nil {
 		return nil, err
 	}
 
-	repo, err := git.PlainOpen(path)
+	// the worktree root (if any), so that the .git file
+	// and the commondir of linked worktrees are followed
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("error opening repository '%s'", path))
 	}
@@ -39,32 +44,19 @@ func OpenGitRepo(path string) (*git.Repository, error) {
 	return repo, nil
 }
 
+// CurrentBranchFromGitRepo returns the short name of the branch
+// HEAD points to, or an empty string if HEAD is detached.
 func CurrentBranchFromGitRepo(repository *git.Repository) (string, error) {
-	branchRefs, err := repository.Branches()
-	if err != nil {
-		return "", err
-	}
-
-	headRef, err := repository.Head()
+	headRef, err := repository.Reference(plumbing.HEAD, false)
 	if err != nil {
 		return "", err
 	}
 
-	var currentBranchName string
-	err = branchRefs.ForEach(func(branchRef *plumbing.Reference) error {
-		if branchRef.Hash() == headRef.Hash() {
-			currentBranchName = branchRef.Name().String()
-
-			return nil
-		}
-
-		return nil
-	})
-	if err != nil {
-		return "", err
+	if headRef.Type() != plumbing.SymbolicReference || !headRef.Target().IsBranch() {
+		return "", nil
 	}
 
-	return currentBranchName, nil
+	return strings.TrimPrefix(headRef.Target().String(), "refs/heads/"), nil
 }
 
 func CurrentCommitFromGitRepo(repository *git.Repository) (string, error) {
@@ -77,116 +69,198 @@ func CurrentCommitFromGitRepo(repository *git.Repository) (string, error) {
 	return headSha, nil
 }
 
+// LatestTagFromGitRepo returns the short name of the tag, reachable
+// from HEAD, with the highest semantic version (see LatestTag),
+// or an empty string if there are no tags.
 func LatestTagFromGitRepo(repository *git.Repository) (string, error) {
-	tagRefs, err := repository.Tags()
+	tags, err := ReachableTags(repository, "")
 	if err != nil {
 		return "", err
 	}
 
-	var latestTagCommit *object.Commit
-	var latestTagName string
-	err = tagRefs.ForEach(func(tagRef *plumbing.Reference) error {
-		revision := plumbing.Revision(tagRef.Name().String())
-		tagCommitHash, err := repository.ResolveRevision(revision)
-		if err != nil {
-			return err
-		}
-
-		commit, err := repository.CommitObject(*tagCommitHash)
-		if err != nil {
-			return err
-		}
+	if tag := LatestTag(tags); tag != nil {
+		return tag.Name, nil
+	}
 
-		if latestTagCommit == nil {
-			latestTagCommit = commit
-			latestTagName = tagRef.Name().String()
-		}
+	return "", nil
+}
 
-		if commit.Committer.When.After(latestTagCommit.Committer.When) {
-			latestTagCommit = commit
-			latestTagName = tagRef.Name().String()
-		}
+// GitRepoURL returns the web URL of the default remote (see SelectRemote).
+func GitRepoURL(repo *git.Repository) (string, error) {
+	return GitRemoteURL(repo, "")
+}
 
-		return nil
-	})
+// GitRemoteURL returns the web URL of the specified remote (the
+// default one if name is empty), or an empty string if there
+// are no remotes.
+func GitRemoteURL(repo *git.Repository, name string) (string, error) {
+	remote, err := SelectRemote(repo, name)
 	if err != nil {
 		return "", err
 	}
 
-	return latestTagName, nil
+	info, err := parseRemote(remote)
+	if err != nil || info == nil {
+		return "", err
+	}
+
+	return info.URL, nil
 }
 
-func GitRepoURL(repo *git.Repository) (string, error) {
+// parseRemote parses the (first URL of the) remote,
+// returning nil if remote is nil or has no URLs.
+func parseRemote(remote *config.RemoteConfig) (*RemoteURL, error) {
+	if remote == nil || len(remote.URLs) == 0 {
+		return nil, nil
+	}
+
+	return ParseRemoteURL(remote.URLs[0])
+}
+
+// defaultRemotes are the remotes looked up, in order,
+// when no one is explicitly requested.
+var defaultRemotes = []string{"origin", "upstream"}
+
+// SelectRemote returns the configuration of the named remote;
+// if name is empty, 'origin', 'upstream' or the first remote
+// in alphabetical order is returned (nil if there are no remotes).
+func SelectRemote(repo *git.Repository, name string) (*config.RemoteConfig, error) {
 	cfg, err := repo.Config()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var res string
-
-	for k, v := range cfg.Remotes {
-		if k == "origin" && len(v.URLs) > 0 {
-			u, err := gitUrls.Parse(v.URLs[0])
-			if err != nil {
-				return "", err
-			}
+	if name != "" {
+		remote, ok := cfg.Remotes[name]
+		if !ok {
+			return nil, fmt.Errorf("remote '%s' not found", name)
+		}
+		return remote, nil
+	}
 
-			res = u.String()
+	for _, el := range defaultRemotes {
+		if remote, ok := cfg.Remotes[el]; ok {
+			return remote, nil
+		}
+	}
 
-			if strings.HasPrefix(u.Scheme, "ssh") {
-				var sb strings.Builder
-				sb.WriteString("https://")
-				sb.WriteString(u.Host)
-				sb.WriteString("/")
-				sb.WriteString(u.Path)
+	names := RemoteNames(cfg)
+	if len(names) == 0 {
+		return nil, nil
+	}
 
-				res = strings.TrimSuffix(sb.String(), ".git")
-			}
+	return cfg.Remotes[names[0]], nil
+}
 
-			break
-		}
+// RemoteNames returns the (sorted) names of the configured remotes.
+func RemoteNames(cfg *config.Config) []string {
+	res := make([]string, 0, len(cfg.Remotes))
+	for k := range cfg.Remotes {
+		res = append(res, k)
 	}
+	sort.Strings(res)
+
+	return res
+}
 
-	return res, nil
+// DetectGitRoot returns the root (the worktree, or the git
+// directory if bare) of the repository containing path.
+func DetectGitRoot(path string) (string, error) {
+	root, _, err := detectGitRepo(path)
+	return root, err
 }
 
+// DetectGitPath returns the git directory of the repository containing
+// path, following the 'gitdir:' pointer of linked worktrees and submodules.
 func DetectGitPath(path string) (string, error) {
+	_, gitDir, err := detectGitRepo(path)
+	return gitDir, err
+}
+
+// detectGitRepo looks for the repository containing path returning
+// its root (the worktree, or the git directory if bare) and git directory.
+func detectGitRepo(path string) (root, gitDir string, err error) {
 	// normalize the path
-	path, err := filepath.Abs(path)
+	path, err = filepath.Abs(path)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
+	// a file inside the repository
+	if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+		path = filepath.Dir(path)
+	}
+	start := path
+
 	for {
-		fi, err := os.Stat(filepath.Join(path, ".git"))
+		dotGit := filepath.Join(path, ".git")
+		fi, err := os.Stat(dotGit)
 		if err == nil {
-			if !fi.IsDir() {
-				return "", fmt.Errorf(".git exist but '%s' is not a directory", path)
+			if fi.IsDir() {
+				return path, dotGit, nil
+			}
+
+			gitDir, err := readGitDirFile(dotGit)
+			if err != nil {
+				return "", "", err
 			}
-			return filepath.Join(path, ".git"), nil
+			return path, gitDir, nil
 		}
 		if !os.IsNotExist(err) {
 			// unknown error
-			return "", err
+			return "", "", err
 		}
 
 		// detect bare repo
 		ok, err := IsGitDir(path)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 		if ok {
-			return path, nil
+			// the .git directory of a plain repo
+			if filepath.Base(path) == ".git" {
+				return filepath.Dir(path), path, nil
+			}
+			return path, path, nil
 		}
 
 		if parent := filepath.Dir(path); parent == path {
-			return "", fmt.Errorf(".git not found in '%s'", path)
+			return "", "", fmt.Errorf("'%s' is not inside a git repository", start)
 		} else {
 			path = parent
 		}
 	}
 }
 
+// readGitDirFile returns the directory a '.git' file points to
+// ('gitdir: <path>', relative to the directory of the file).
+func readGitDirFile(filename string) (string, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	line := strings.TrimSpace(strings.SplitN(string(buf), "\n", 2)[0])
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("'%s' is not a directory nor a gitdir file", filename)
+	}
+
+	gitDir := filepath.FromSlash(strings.TrimSpace(strings.TrimPrefix(line, "gitdir:")))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(filename), gitDir)
+	}
+
+	fi, err := os.Stat(gitDir)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("'%s' points to a missing git directory", filename))
+	}
+	if !fi.IsDir() {
+		return "", fmt.Errorf("'%s' points to '%s' which is not a directory", filename, gitDir)
+	}
+
+	return gitDir, nil
+}
+
 func IsGitDir(path string) (bool, error) {
 	markers := []string{"HEAD", "objects", "refs"}
 
@@ -205,3 +279,29 @@ func IsGitDir(path string) (bool, error) {
 
 	return true, nil
 }
+
+// FileFromGitRepo returns the content of the file at path
+// in the tree of the specified revision (branch, tag or commit).
+func FileFromGitRepo(repository *git.Repository, rev, path string) (io.ReadCloser, error) {
+	hash, err := repository.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error resolving revision '%s'", rev))
+	}
+
+	commit, err := repository.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := tree.File(strings.TrimPrefix(filepath.ToSlash(path), "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error reading '%s' at revision '%s'", path, rev))
+	}
+
+	return file.Reader()
+}
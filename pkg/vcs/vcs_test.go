@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/go-git/go-git/v5/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -52,3 +53,75 @@ func TestNewGitRepo(t *testing.T) {
 		}
 	}
 }
+
+// remote adds a remote to the repository.
+func (r *testRepo) remote(name, url string) {
+	_, err := r.repo.CreateRemote(&config.RemoteConfig{Name: name, URLs: []string{url}})
+	require.NoError(r.t, err)
+}
+
+func TestSelectRemote(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("README.md", "hello", "first")
+
+	remote, err := SelectRemote(r.repo, "")
+	require.NoError(t, err)
+	assert.Nil(t, remote)
+
+	r.remote("mirror", "https://git.example.com/lucasepe/tbd.git")
+	r.remote("backup", "https://backup.example.com/lucasepe/tbd.git")
+
+	// first in alphabetical order
+	remote, err = SelectRemote(r.repo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "backup", remote.Name)
+
+	r.remote("upstream", "git@github.com:lucasepe/tbd.git")
+	remote, err = SelectRemote(r.repo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", remote.Name)
+
+	r.remote("origin", "https://github.com/pinco/tbd.git")
+	remote, err = SelectRemote(r.repo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "origin", remote.Name)
+
+	remote, err = SelectRemote(r.repo, "mirror")
+	require.NoError(t, err)
+	assert.Equal(t, "mirror", remote.Name)
+
+	_, err = SelectRemote(r.repo, "missing")
+	assert.Error(t, err)
+
+	url, err := GitRemoteURL(r.repo, "upstream")
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/lucasepe/tbd", url)
+}
+
+func TestRemoteMetadata(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("README.md", "hello", "first")
+	r.remote("upstream", "git@github.com:lucasepe/tbd.git")
+	r.remote("fork", "https://github.com/pinco/tbd.git")
+
+	meta := r.metadata()
+	assert.Equal(t, "fork,upstream", meta[RepoRemotes])
+	assert.Equal(t, "upstream", meta[RepoRemote])
+	assert.Equal(t, "https://github.com/lucasepe/tbd", meta[RepoURL])
+	assert.Equal(t, "lucasepe", meta[RepoRoot])
+	assert.Equal(t, "tbd", meta[RepoName])
+
+	meta = map[string]string{}
+	require.NoError(t, GitRepoMetadataWithOptions(r.dir, Options{Remote: "fork"}, meta))
+	assert.Equal(t, "fork", meta[RepoRemote])
+	assert.Equal(t, "pinco", meta[RepoRoot])
+
+	meta = map[string]string{}
+	warnings, err := CollectGitRepoMetadata(r.dir, Options{Remote: "missing"}, meta)
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+	assert.EqualError(t, warnings[0], "tag: no tags reachable from HEAD")
+	assert.EqualError(t, warnings[1], "remote: remote 'missing' not found")
+	assert.Equal(t, "fork,upstream", meta[RepoRemotes])
+	assert.Empty(t, meta[RepoURL])
+}